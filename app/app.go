@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/pubsub"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"github.com/twibber/core/mail"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"math/rand/v2"
+	"time"
+)
+
+// App holds the dependencies shared by the whole application, it is built once in main and passed to every handler.
+// Tests and tools can build their own App with whichever dependencies they need.
+type App struct {
+	Config *cfg.Configuration
+	DB     *gorm.DB
+	Mailer *mail.Mailer
+
+	// Replicas serve the busy read endpoints through Read, there are none unless they are configured.
+	Replicas []*gorm.DB
+
+	// Repos are the repositories of the core models over DB, use ReadRepos for reads that can come from a replica.
+	Repos *repository.Repositories
+
+	// Broker delivers live events to the streams, and Events works out who should receive them.
+	Broker pubsub.Broker
+	Events *pubsub.Publisher
+}
+
+// New connects to the database, migrates it when configured to, and sets up the mailer and the event broker.
+func New(config *cfg.Configuration) (*App, error) {
+	conn, err := db.Open(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Post connection we can migrate the database, or make sure it has already been migrated.
+	if config.DBMigrate {
+		if err := db.MigrateDB(conn); err != nil {
+			return nil, err
+		}
+	} else {
+		migrator, err := db.NewMigrator(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		pending, err := migrator.Pending()
+		if err != nil {
+			return nil, err
+		}
+		if pending > 0 {
+			return nil, fmt.Errorf("the database has %d pending migrations, run the migrate up command first", pending)
+		}
+	}
+
+	replicas, err := db.OpenReplicas(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := mail.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	broker := pubsub.NewHub()
+
+	return &App{
+		Config:   config,
+		DB:       conn,
+		Mailer:   mailer,
+		Replicas: replicas,
+		Repos:    repository.New(conn),
+		Broker:   broker,
+		Events:   pubsub.NewPublisher(conn, broker),
+	}, nil
+}
+
+// Read returns the database the request's reads should come from.
+// Reads are spread across the replicas, unless the user wrote recently and must see their own writes on the primary.
+// Writes and transactions must always use DB.
+func (a *App) Read(c *fiber.Ctx) *gorm.DB {
+	if len(a.Replicas) == 0 || a.pinnedToPrimary(c) {
+		return a.DB
+	}
+
+	return a.Replicas[rand.IntN(len(a.Replicas))]
+}
+
+// ReadRepos returns the repositories over the database the request's reads should come from, see Read.
+func (a *App) ReadRepos(c *fiber.Ctx) *repository.Repositories {
+	reader := a.Read(c)
+	if reader == a.DB {
+		return a.Repos
+	}
+
+	return repository.New(reader)
+}
+
+// pinnedToPrimary checks whether the signed in user wrote within DB_PRIMARY_PIN_WINDOW.
+// The pin is kept on the session, so it comes with the session lookup the request makes anyway.
+func (a *App) pinnedToPrimary(c *fiber.Ctx) bool {
+	session, ok := utils.GetSession(c, a.Repos.Sessions)
+	return ok && session.PrimaryUntil != nil && time.Now().Before(*session.PrimaryUntil)
+}
+
+// PinToPrimary pins the reads of every session of the user to the primary database for DB_PRIMARY_PIN_WINDOW, after they wrote.
+func (a *App) PinToPrimary(userID string) error {
+	return a.Repos.Sessions.PinToPrimary(userID, time.Now().Add(a.Config.DBPrimaryPinWindow))
+}
@@ -0,0 +1,222 @@
+// Package apptest builds the whole HTTP application against an isolated database for integration tests,
+// with factories for the core models and helpers to make requests and decode the responses.
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/pubsub"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/app/routes"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"github.com/twibber/core/mail"
+	"github.com/twibber/core/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Harness is the application under test, with its own in-memory database that is thrown away with the test.
+type Harness struct {
+	t testing.TB
+
+	App   *app.App
+	Fiber *fiber.App
+}
+
+// New builds the application for the test, the options can change the configuration before anything is set up.
+//
+// The configuration is loaded from the environment like it is in main, with debug mode on so emails are only logged,
+// and cheap password hashing so the tests stay fast. It uses t.Setenv, so tests using the harness cannot be parallel.
+//
+// The database is closed when the test ends. Notifications and live events are sent before a handler responds,
+// so nothing a request started is still using it by then.
+func New(t testing.TB, options ...func(config *cfg.Configuration)) *Harness {
+	t.Helper()
+
+	env := map[string]string{
+		"DEBUG":            "true",
+		"DOMAIN":           "localhost",
+		"DB_HOST":          "localhost",
+		"DB_USERNAME":      "twibber",
+		"DB_DATABASE":      "twibber",
+		"DB_REPLICAS":      "",
+		"ARGON_MEMORY":     "8192",
+		"ARGON_ITERATIONS": "1",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	config := &cfg.Configuration{}
+	if err := cfg.LoadConfiguration(config); err != nil {
+		t.Fatalf("apptest: load configuration: %v", err)
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	// Every in-memory database is private to its connection, and SQLite only uses one, so each test gets its own.
+	conn, err := db.OpenSQLite("file::memory:")
+	if err != nil {
+		t.Fatalf("apptest: open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	mailer, err := mail.New(config)
+	if err != nil {
+		t.Fatalf("apptest: create mailer: %v", err)
+	}
+
+	broker := pubsub.NewHub()
+	a := &app.App{
+		Config: config,
+		DB:     conn,
+		Mailer: mailer,
+		Repos:  repository.New(conn),
+		Broker: broker,
+		Events: pubsub.NewPublisher(conn, broker),
+	}
+
+	return &Harness{
+		t:     t,
+		App:   a,
+		Fiber: routes.Configure(a),
+	}
+}
+
+// Do makes a request to the application, encoding the body as JSON unless it is nil, with the cookies attached.
+func (h *Harness) Do(method, path string, body any, cookies ...*http.Cookie) *http.Response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("apptest: encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	// Password hashing can take longer than the default timeout of a second, so there is none.
+	resp, err := h.Fiber.Test(req, -1)
+	if err != nil {
+		h.t.Fatalf("apptest: %s %s: %v", method, path, err)
+	}
+	h.t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+
+	return resp
+}
+
+// Get makes a GET request to the application.
+func (h *Harness) Get(path string, cookies ...*http.Cookie) *http.Response {
+	h.t.Helper()
+	return h.Do(http.MethodGet, path, nil, cookies...)
+}
+
+// Post makes a POST request to the application with the body encoded as JSON.
+func (h *Harness) Post(path string, body any, cookies ...*http.Cookie) *http.Response {
+	h.t.Helper()
+	return h.Do(http.MethodPost, path, body, cookies...)
+}
+
+// Patch makes a PATCH request to the application with the body encoded as JSON.
+func (h *Harness) Patch(path string, body any, cookies ...*http.Cookie) *http.Response {
+	h.t.Helper()
+	return h.Do(http.MethodPatch, path, body, cookies...)
+}
+
+// Delete makes a DELETE request to the application.
+func (h *Harness) Delete(path string, cookies ...*http.Cookie) *http.Response {
+	h.t.Helper()
+	return h.Do(http.MethodDelete, path, nil, cookies...)
+}
+
+// ExpectStatus fails the test if the response does not have the status, reporting the body to help find out why.
+func (h *Harness) ExpectStatus(resp *http.Response, status int) {
+	h.t.Helper()
+
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		h.t.Fatalf("apptest: expected status %d, got %d: %s", status, resp.StatusCode, body)
+	}
+}
+
+// DecodeJSON expects the response to have the status, then decodes its JSON body into v.
+func (h *Harness) DecodeJSON(resp *http.Response, status int, v any) {
+	h.t.Helper()
+
+	h.ExpectStatus(resp, status)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		h.t.Fatalf("apptest: decode response: %v", err)
+	}
+}
+
+// DecodeError expects the response to be an error with the status, then decodes it as returned by utils.ErrorHandler.
+func (h *Harness) DecodeError(resp *http.Response, status int) utils.Error {
+	h.t.Helper()
+
+	var e utils.Error
+	h.DecodeJSON(resp, status, &e)
+
+	// The status is not part of the body, it is the status of the response.
+	e.Status = resp.StatusCode
+
+	return e
+}
+
+// ExpectError expects the response to be an error with the status and code, returning it to check anything else.
+func (h *Harness) ExpectError(resp *http.Response, status int, code string) utils.Error {
+	h.t.Helper()
+
+	e := h.DecodeError(resp, status)
+	if e.Code != code {
+		h.t.Fatalf("apptest: expected error code %s, got %s: %s", code, e.Code, e.Message)
+	}
+
+	return e
+}
+
+// Cookie gets the cookie the response set, or nil if it did not set it.
+func Cookie(resp *http.Response, name string) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+// HasFieldError checks whether the error has a problem with the named field.
+func HasFieldError(e utils.Error, field string) bool {
+	if e.Details == nil {
+		return false
+	}
+
+	for _, f := range e.Details.Fields {
+		if f.Name == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,147 @@
+package apptest
+
+import (
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"net/http"
+	"time"
+)
+
+// Password is the password of every connection created by the factories.
+const Password = "correct-horse-battery"
+
+// CreateUser creates a user without any connections, with the email <username>@example.com.
+// The options can change the user before it is created.
+func (h *Harness) CreateUser(username string, options ...func(user *models.User)) models.User {
+	h.t.Helper()
+
+	user := models.User{
+		Username:    username,
+		DisplayName: username,
+		Email:       username + "@example.com",
+	}
+	for _, option := range options {
+		option(&user)
+	}
+
+	if err := h.App.DB.Create(&user).Error; err != nil {
+		h.t.Fatalf("apptest: create user %s: %v", username, err)
+	}
+
+	return user
+}
+
+// CreateConnection creates the email connection of the user, with Password as its password.
+// Unverified connections can only use the endpoints that do not require a verified account.
+func (h *Harness) CreateConnection(user models.User, verified bool) models.Connection {
+	h.t.Helper()
+
+	hash, err := utils.CreateHash(Password, utils.ArgonParamsFromConfig(h.App.Config))
+	if err != nil {
+		h.t.Fatalf("apptest: hash password: %v", err)
+	}
+
+	secret, err := utils.GenerateSecureRandomBase32(32)
+	if err != nil {
+		h.t.Fatalf("apptest: generate secret: %v", err)
+	}
+
+	connection := models.Connection{
+		BaseModel: models.BaseModel{
+			ID: models.ProviderEmailType.WithID(user.Email),
+		},
+		Password:   hash,
+		Verified:   verified,
+		TOTPVerify: secret,
+		UserID:     user.ID,
+	}
+	if err := h.App.DB.Create(&connection).Error; err != nil {
+		h.t.Fatalf("apptest: create connection for %s: %v", user.Username, err)
+	}
+
+	return connection
+}
+
+// CreateSession signs the connection in, returning the Authorization cookie to make requests with.
+func (h *Harness) CreateSession(connection models.Connection) *http.Cookie {
+	h.t.Helper()
+
+	return h.createSession(connection, time.Now().Add(h.App.Config.AuthDuration))
+}
+
+// CreateExpiredSession creates a session for the connection that has already expired, returning its cookie.
+func (h *Harness) CreateExpiredSession(connection models.Connection) *http.Cookie {
+	h.t.Helper()
+
+	return h.createSession(connection, time.Now().Add(-time.Minute))
+}
+
+func (h *Harness) createSession(connection models.Connection, expiresAt time.Time) *http.Cookie {
+	h.t.Helper()
+
+	session := models.Session{
+		BaseModel: models.BaseModel{
+			ID: utils.GenerateString(64),
+		},
+		ConnectionID: connection.ID,
+		ExpiresAt:    expiresAt,
+	}
+	if err := h.App.DB.Create(&session).Error; err != nil {
+		h.t.Fatalf("apptest: create session: %v", err)
+	}
+
+	return &http.Cookie{Name: utils.AuthCookieName, Value: session.ID}
+}
+
+// SignUp creates a user with a verified connection and signs them in, returning the user and their cookie.
+func (h *Harness) SignUp(username string) (models.User, *http.Cookie) {
+	h.t.Helper()
+
+	user := h.CreateUser(username)
+	return user, h.CreateSession(h.CreateConnection(user, true))
+}
+
+// CreatePost creates a published public post by the author, the options can change the post before it is created.
+func (h *Harness) CreatePost(author models.User, content string, options ...func(post *models.Post)) models.Post {
+	h.t.Helper()
+
+	post := models.Post{
+		AuthorID:   author.ID,
+		Content:    content,
+		Status:     models.PostStatusPublished,
+		Visibility: models.VisibilityPublic,
+	}
+	for _, option := range options {
+		option(&post)
+	}
+
+	if err := h.App.DB.Create(&post).Error; err != nil {
+		h.t.Fatalf("apptest: create post: %v", err)
+	}
+
+	return post
+}
+
+// CreateReply creates a published public reply by the author to the post.
+func (h *Harness) CreateReply(author models.User, parent models.Post, content string) models.Post {
+	h.t.Helper()
+
+	return h.CreatePost(author, content, func(post *models.Post) {
+		post.ParentID = &parent.ID
+	})
+}
+
+// CreateLike likes the post as the user.
+func (h *Harness) CreateLike(user models.User, post models.Post) models.Like {
+	h.t.Helper()
+
+	like := models.Like{
+		LikedByID: user.ID,
+		PostID:    post.ID,
+	}
+	if err := h.App.DB.Create(&like).Error; err != nil {
+		h.t.Fatalf("apptest: create like: %v", err)
+	}
+
+	return like
+}
@@ -3,33 +3,101 @@ package account
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
 	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
 )
 
 // GetSession returns the session of the currently authenticated user
-func GetSession(c *fiber.Ctx) error {
+func (h *Handler) GetSession(c *fiber.Ctx) error {
 	// Just extract the attached session from the context and return it
 	return c.JSON(c.Locals("session").(models.Session))
 }
 
-// UpdateProfile Not implemented but we have the route defined
-func UpdateProfile(c *fiber.Ctx) error {
-	return utils.ErrNotImplemented
+// UpdateProfileForm is used to parse the request body for profile updates, fields that are not provided are left unchanged.
+type UpdateProfileForm struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=512"`
+	Protected   *bool   `json:"protected"`
+
+	DMFollowersOnly *bool `json:"dm_followers_only"`
+}
+
+// UpdateProfile updates the profile of the currently authenticated user
+func (h *Handler) UpdateProfile(c *fiber.Ctx) error {
+	// Get the session from the context
+	session := c.Locals("session").(models.Session)
+
+	// Get the request body and validate it.
+	var body UpdateProfileForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Only update the fields that were provided
+	updates := make(map[string]interface{})
+	if body.DisplayName != nil {
+		updates["display_name"] = *body.DisplayName
+	}
+	if body.Protected != nil {
+		updates["protected"] = *body.Protected
+	}
+	if body.DMFollowersOnly != nil {
+		updates["dm_followers_only"] = *body.DMFollowersOnly
+	}
+
+	if len(updates) == 0 {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", session.Connection.UserID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// When an account stops being protected, every pending follow request is approved.
+		if body.Protected != nil && !*body.Protected {
+			var requests []models.FollowRequest
+			if err := tx.Where(models.FollowRequest{TargetID: session.Connection.UserID}).
+				Find(&requests).Error; err != nil {
+				return err
+			}
+
+			for _, request := range requests {
+				if err := tx.Create(&models.Follow{
+					FollowerID:  request.RequesterID,
+					FollowingID: request.TargetID,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Where(models.FollowRequest{TargetID: session.Connection.UserID}).
+				Delete(&models.FollowRequest{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
 }
 
 // Logout logs the user out by deleting the session from the database and clearing the auth cookie
-func Logout(c *fiber.Ctx) error {
+func (h *Handler) Logout(c *fiber.Ctx) error {
 	// Get the session from the context
 	session := c.Locals("session").(models.Session)
 
 	// delete the session from the database
-	if err := db.DB.Delete(&session).Error; err != nil {
+	if err := h.Repos.Sessions.Delete(session.ID); err != nil {
 		return err
 	}
 
 	// Clear the auth cookie
-	utils.ClearAuth(c)
+	utils.ClearAuth(c, h.Config.Domain)
 
 	// Return OK
 	return c.SendStatus(fiber.StatusOK)
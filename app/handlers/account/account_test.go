@@ -0,0 +1,129 @@
+package account_test
+
+import (
+	"github.com/twibber/core/app/apptest"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetSession(t *testing.T) {
+	h := apptest.New(t)
+	user, cookie := h.SignUp("alice")
+
+	var session models.Session
+	h.DecodeJSON(h.Get("/account", cookie), http.StatusOK, &session)
+
+	if session.ID != cookie.Value {
+		t.Errorf("expected session %s, got %s", cookie.Value, session.ID)
+	}
+	if session.Connection == nil || session.Connection.User == nil || session.Connection.User.ID != user.ID {
+		t.Fatalf("expected the session of %s, got %+v", user.ID, session)
+	}
+	if session.Connection.User.Email != user.Email {
+		t.Errorf("expected the user's own email %s, got %q", user.Email, session.Connection.User.Email)
+	}
+}
+
+func TestGetSessionUnauthorised(t *testing.T) {
+	h := apptest.New(t)
+	connection := h.CreateConnection(h.CreateUser("alice"), true)
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+	}{
+		{"no cookie", nil},
+		{"unknown session", []*http.Cookie{{Name: utils.AuthCookieName, Value: "unknown"}}},
+		{"expired session", []*http.Cookie{h.CreateExpiredSession(connection)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h.ExpectError(h.Get("/account", test.cookies...), http.StatusUnauthorized, "UNAUTHORIZED")
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	h := apptest.New(t)
+	user, cookie := h.SignUp("alice")
+
+	h.ExpectStatus(h.Patch("/account", map[string]any{
+		"display_name": "Alice Liddell",
+		"protected":    true,
+	}, cookie), http.StatusOK)
+
+	var updated models.User
+	if err := h.App.DB.First(&updated, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if updated.DisplayName != "Alice Liddell" || !updated.Protected {
+		t.Errorf("expected the profile to be updated, got %+v", updated)
+	}
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	h := apptest.New(t)
+	_, cookie := h.SignUp("alice")
+
+	e := h.ExpectError(h.Patch("/account", map[string]any{
+		"display_name": strings.Repeat("a", 513),
+	}, cookie), http.StatusBadRequest, "BAD_REQUEST")
+
+	if !apptest.HasFieldError(e, "display_name") {
+		t.Errorf("expected an error for display_name, got %+v", e.Details)
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	h := apptest.New(t)
+	_, cookie := h.SignUp("alice")
+
+	h.ExpectStatus(h.Patch("/account/password", map[string]string{
+		"password":     apptest.Password,
+		"new_password": "a-brand-new-password",
+	}, cookie), http.StatusOK)
+
+	// Only the new password works from now on.
+	h.ExpectError(h.Post("/auth/login", map[string]string{
+		"email":    "alice@example.com",
+		"password": apptest.Password,
+	}), http.StatusUnauthorized, utils.ErrInvalidCredentials.Code)
+
+	h.ExpectStatus(h.Post("/auth/login", map[string]string{
+		"email":    "alice@example.com",
+		"password": "a-brand-new-password",
+	}), http.StatusCreated)
+}
+
+func TestUpdatePasswordIncorrect(t *testing.T) {
+	h := apptest.New(t)
+	_, cookie := h.SignUp("alice")
+
+	e := h.ExpectError(h.Patch("/account/password", map[string]string{
+		"password":     "not-the-password",
+		"new_password": "a-brand-new-password",
+	}, cookie), http.StatusBadRequest, "BAD_REQUEST")
+
+	if !apptest.HasFieldError(e, "password") {
+		t.Errorf("expected an error for password, got %+v", e.Details)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	h := apptest.New(t)
+	_, cookie := h.SignUp("alice")
+
+	resp := h.Post("/account/logout", nil, cookie)
+	h.ExpectStatus(resp, http.StatusOK)
+
+	if cleared := apptest.Cookie(resp, utils.AuthCookieName); cleared == nil || cleared.Value != "" {
+		t.Errorf("expected the Authorization cookie to be cleared, got %+v", cleared)
+	}
+
+	// The session is gone, so the old cookie no longer works.
+	h.ExpectError(h.Get("/account", cookie), http.StatusUnauthorized, "UNAUTHORIZED")
+}
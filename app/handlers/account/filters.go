@@ -0,0 +1,110 @@
+package account
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"strings"
+	"time"
+)
+
+// FilterForm is used to parse the request body for new mute filters.
+type FilterForm struct {
+	Keyword   string              `json:"keyword" validate:"required,max=128"`
+	Scope     models.FilterScope  `json:"scope" validate:"omitempty,oneof=home replies notifications all"`
+	Action    models.FilterAction `json:"action" validate:"omitempty,oneof=hide warn"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+}
+
+// ListFilters returns the current user's mute filters, including the ones that have expired.
+func (h *Handler) ListFilters(c *fiber.Ctx) error {
+	// Get the session from the context
+	session := c.Locals("session").(models.Session)
+
+	var filters []models.MuteFilter
+	if err := h.DB.
+		Where(models.MuteFilter{UserID: session.Connection.UserID}).
+		Order("created_at desc").
+		Find(&filters).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(filters)
+}
+
+// CreateFilter creates a new mute filter for the current user.
+func (h *Handler) CreateFilter(c *fiber.Ctx) error {
+	// Get the session from the context
+	session := c.Locals("session").(models.Session)
+
+	// Get the request body and validate it.
+	var body FilterForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	keyword := strings.TrimSpace(body.Keyword)
+	if keyword == "" {
+		return utils.NewError(fiber.StatusBadRequest, "The keyword provided is empty.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "keyword",
+					Errors: []string{"The keyword provided is empty."},
+				},
+			},
+		})
+	}
+
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		return utils.NewError(fiber.StatusBadRequest, "The expiry time must be in the future.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "expires_at",
+					Errors: []string{"The expiry time must be in the future."},
+				},
+			},
+		})
+	}
+
+	// Default to hiding matches everywhere.
+	if body.Scope == "" {
+		body.Scope = models.FilterScopeAll
+	}
+	if body.Action == "" {
+		body.Action = models.FilterActionHide
+	}
+
+	filter := models.MuteFilter{
+		UserID:    session.Connection.UserID,
+		Keyword:   keyword,
+		Scope:     body.Scope,
+		Action:    body.Action,
+		ExpiresAt: body.ExpiresAt,
+	}
+	if err := h.DB.Create(&filter).Error; err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(filter)
+}
+
+// DeleteFilter deletes one of the current user's mute filters.
+func (h *Handler) DeleteFilter(c *fiber.Ctx) error {
+	// Get the session from the context
+	session := c.Locals("session").(models.Session)
+
+	// Delete the filter, only the current user's filters can be deleted.
+	result := h.DB.Where(models.MuteFilter{
+		BaseModel: models.BaseModel{ID: c.Params("filter")},
+		UserID:    session.Connection.UserID,
+	}).Delete(&models.MuteFilter{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,90 @@
+package account
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+)
+
+// ListFollowRequests returns the pending requests to follow the current user, oldest first.
+func (h *Handler) ListFollowRequests(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Get the current session.
+	session := c.Locals("session").(models.Session)
+
+	var requests []models.FollowRequest
+	if err := h.DB.
+		Preload("Requester", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("Email") // Omit the email of the requester for privacy reasons.
+		}).
+		Where(models.FollowRequest{TargetID: session.Connection.UserID}).
+		Order("created_at asc").
+		Scopes(page.Paginate).
+		Find(&requests).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(requests)
+}
+
+// ApproveFollowRequest approves a pending follow request, making the requester a follower of the current user.
+func (h *Handler) ApproveFollowRequest(c *fiber.Ctx) error {
+	// Get the current session.
+	session := c.Locals("session").(models.Session)
+
+	// Replace the request with the follow in a single transaction.
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Get the request by its ID, only requests to follow the current user can be approved.
+		var request models.FollowRequest
+		if err := tx.Where(models.FollowRequest{
+			BaseModel: models.BaseModel{ID: c.Params("request")},
+			TargetID:  session.Connection.UserID,
+		}).First(&request).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.Follow{
+			FollowerID:  request.RequesterID,
+			FollowingID: request.TargetID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&request).Error; err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// RejectFollowRequest rejects a pending follow request by deleting it.
+func (h *Handler) RejectFollowRequest(c *fiber.Ctx) error {
+	// Get the current session.
+	session := c.Locals("session").(models.Session)
+
+	// Delete the request, only requests to follow the current user can be rejected.
+	result := h.DB.Where(models.FollowRequest{
+		BaseModel: models.BaseModel{ID: c.Params("request")},
+		TargetID:  session.Connection.UserID,
+	}).Delete(&models.FollowRequest{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,13 @@
+package account
+
+import "github.com/twibber/core/app"
+
+// Handler serves the account endpoints.
+type Handler struct {
+	*app.App
+}
+
+// New creates the account handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
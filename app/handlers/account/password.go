@@ -3,7 +3,6 @@ package account
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
 	"github.com/twibber/core/utils"
 )
 
@@ -12,7 +11,7 @@ type UpdatePasswordDTO struct {
 	Password        string `json:"new_password" validate:"required"`
 }
 
-func UpdatePassword(c *fiber.Ctx) error {
+func (h *Handler) UpdatePassword(c *fiber.Ctx) error {
 	session := c.Locals("session").(models.Session)
 	connection := session.Connection
 
@@ -37,13 +36,13 @@ func UpdatePassword(c *fiber.Ctx) error {
 		})
 	}
 
-	hash, err := utils.CreateHash(dto.Password)
+	hash, err := utils.CreateHash(dto.Password, utils.ArgonParamsFromConfig(h.Config))
 	if err != nil {
 		return err
 	}
 	connection.Password = hash
 
-	if err := db.DB.Save(&connection).Error; err != nil {
+	if err := h.DB.Save(&connection).Error; err != nil {
 		return err
 	}
 
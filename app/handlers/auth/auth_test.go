@@ -0,0 +1,240 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"github.com/twibber/core/app/apptest"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/mail"
+	"github.com/twibber/core/utils"
+	"net/http"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	h := apptest.New(t)
+
+	resp := h.Post("/auth/register", map[string]string{
+		"display_name": "Alice",
+		"username":     "alice",
+		"email":        "alice@example.com",
+		"password":     apptest.Password,
+	})
+	h.ExpectStatus(resp, http.StatusCreated)
+
+	cookie := apptest.Cookie(resp, utils.AuthCookieName)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("expected the Authorization cookie to be set")
+	}
+
+	// The new account can be used straight away, but is not verified.
+	var session models.Session
+	h.DecodeJSON(h.Get("/account", cookie), http.StatusOK, &session)
+	if session.Connection == nil || session.Connection.User == nil || session.Connection.User.Username != "alice" {
+		t.Fatalf("expected the session of alice, got %+v", session)
+	}
+	if session.Connection.Verified {
+		t.Fatal("expected the connection to be unverified")
+	}
+
+	// The verification email waits in the outbox to be sent.
+	expectQueuedEmails(t, h, "alice@example.com", 1)
+}
+
+// expectQueuedEmails checks the number of verification emails waiting in the outbox for the recipient.
+func expectQueuedEmails(t *testing.T, h *apptest.Harness, recipient string, count int) {
+	t.Helper()
+
+	var emails []models.OutboundEmail
+	if err := h.App.DB.Where(models.OutboundEmail{Recipient: recipient}).Find(&emails).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(emails) != count {
+		t.Fatalf("expected %d queued emails for %s, got %d", count, recipient, len(emails))
+	}
+	for _, email := range emails {
+		if email.Status != models.OutboundEmailPending || email.Template != "user_verify" {
+			t.Errorf("expected a pending verification email, got %s %s", email.Status, email.Template)
+		}
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	h := apptest.New(t)
+
+	e := h.ExpectError(h.Post("/auth/register", map[string]string{
+		"display_name": "Alice",
+		"username":     "Alice123",
+		"email":        "not an email",
+		"password":     "short",
+	}), http.StatusBadRequest, "BAD_REQUEST")
+
+	for _, field := range []string{"username", "email", "password"} {
+		if !apptest.HasFieldError(e, field) {
+			t.Errorf("expected an error for %s, got %+v", field, e.Details)
+		}
+	}
+}
+
+func TestRegisterConflicts(t *testing.T) {
+	h := apptest.New(t)
+	h.CreateUser("alice")
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		field    string
+	}{
+		{"email", "bob", "alice@example.com", "email"},
+		{"username", "alice", "bob@example.com", "username"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := h.ExpectError(h.Post("/auth/register", map[string]string{
+				"display_name": "Bob",
+				"username":     test.username,
+				"email":        test.email,
+				"password":     apptest.Password,
+			}), http.StatusConflict, "CONFLICT")
+
+			if !apptest.HasFieldError(e, test.field) {
+				t.Errorf("expected an error for %s, got %+v", test.field, e.Details)
+			}
+			expectQueuedEmails(t, h, test.email, 0)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	h := apptest.New(t)
+	h.CreateConnection(h.CreateUser("alice"), true)
+
+	resp := h.Post("/auth/login", map[string]string{
+		"email":    "alice@example.com",
+		"password": apptest.Password,
+	})
+	h.ExpectStatus(resp, http.StatusCreated)
+
+	cookie := apptest.Cookie(resp, utils.AuthCookieName)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("expected the Authorization cookie to be set")
+	}
+	h.ExpectStatus(h.Get("/account", cookie), http.StatusOK)
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	h := apptest.New(t)
+	h.CreateConnection(h.CreateUser("alice"), true)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"wrong password", "alice@example.com", "not-the-password"},
+		{"unknown email", "nobody@example.com", apptest.Password},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := h.Post("/auth/login", map[string]string{
+				"email":    test.email,
+				"password": test.password,
+			})
+
+			// Unknown emails look the same as wrong passwords, so accounts cannot be discovered.
+			e := h.ExpectError(resp, http.StatusUnauthorized, utils.ErrInvalidCredentials.Code)
+			if e.Message != utils.ErrInvalidCredentials.Message {
+				t.Errorf("expected the invalid credentials error, got %q", e.Message)
+			}
+			if apptest.Cookie(resp, utils.AuthCookieName) != nil {
+				t.Error("expected no Authorization cookie")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	h := apptest.New(t)
+	connection := h.CreateConnection(h.CreateUser("alice"), false)
+	cookie := h.CreateSession(connection)
+
+	// Unverified accounts cannot post.
+	h.ExpectError(h.Post("/posts", map[string]string{"content": "hello"}, cookie), http.StatusForbidden, "UNVERIFIED")
+
+	code, err := utils.GenerateTOTP(connection.TOTPVerify, h.App.Config.EmailCodeStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ExpectStatus(h.Post("/account/verify", map[string]string{"code": code}, cookie), http.StatusOK)
+
+	var session models.Session
+	h.DecodeJSON(h.Get("/account", cookie), http.StatusOK, &session)
+	if !session.Connection.Verified {
+		t.Fatal("expected the connection to be verified")
+	}
+
+	h.ExpectStatus(h.Post("/posts", map[string]string{"content": "hello"}, cookie), http.StatusOK)
+}
+
+func TestVerifyInvalidCode(t *testing.T) {
+	h := apptest.New(t)
+	cookie := h.CreateSession(h.CreateConnection(h.CreateUser("alice"), false))
+
+	e := h.ExpectError(h.Post("/account/verify", map[string]string{"code": "000000"}, cookie), http.StatusBadRequest, "BAD_REQUEST")
+	if !apptest.HasFieldError(e, "code") {
+		t.Errorf("expected an error for code, got %+v", e.Details)
+	}
+
+	e = h.ExpectError(h.Post("/account/verify", map[string]string{"code": "1"}, cookie), http.StatusBadRequest, "BAD_REQUEST")
+	if !apptest.HasFieldError(e, "code") {
+		t.Errorf("expected an error for code, got %+v", e.Details)
+	}
+}
+
+func TestVerifyRequiresSession(t *testing.T) {
+	h := apptest.New(t)
+
+	h.ExpectError(h.Post("/account/verify", map[string]string{"code": "000000"}), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.ExpectError(h.Post("/account/resend", nil), http.StatusUnauthorized, "UNAUTHORIZED")
+}
+
+func TestResendCode(t *testing.T) {
+	h := apptest.New(t)
+	cookie := h.CreateSession(h.CreateConnection(h.CreateUser("alice"), false))
+
+	h.ExpectStatus(h.Post("/account/resend", nil, cookie), http.StatusOK)
+	expectQueuedEmails(t, h, "alice@example.com", 1)
+}
+
+func TestVerificationCodeGeneratedOnSend(t *testing.T) {
+	h := apptest.New(t)
+	connection := h.CreateConnection(h.CreateUser("alice"), false)
+	cookie := h.CreateSession(connection)
+
+	h.ExpectStatus(h.Post("/account/resend", nil, cookie), http.StatusOK)
+
+	var email models.OutboundEmail
+	if err := h.App.DB.Where(models.OutboundEmail{Recipient: "alice@example.com"}).First(&email).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The outbox only holds the connection, never the code.
+	var data mail.VerifyDTO
+	if err := json.Unmarshal([]byte(email.Data), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.ConnectionID != connection.ID || data.Code != "" {
+		t.Fatalf("expected only the connection to be queued, got %s", email.Data)
+	}
+
+	// The code is generated as the email is sent, so it is valid however long the email waited.
+	if err := data.Prepare(h.App.DB, h.App.Config); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.ValidateTOTP(connection.TOTPVerify, data.Code, h.App.Config.EmailCodeStep) {
+		t.Errorf("expected the prepared code %q to be valid", data.Code)
+	}
+}
@@ -3,10 +3,10 @@ package auth
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
+	"github.com/twibber/core/app/repository"
 	"github.com/twibber/core/mail"
 	"github.com/twibber/core/utils"
-	"log/slog"
+	"gorm.io/gorm"
 	"net/http"
 	"time"
 )
@@ -27,23 +27,21 @@ type RegisterForm struct {
 }
 
 // Register handles the registration of new users.
-func Register(c *fiber.Ctx) error {
+func (h *Handler) Register(c *fiber.Ctx) error {
 	// Get the request body and validate it.
 	var body RegisterForm
 	if err := utils.ParseAndValidate(c, &body); err != nil {
 		return err
 	}
 
-	// Count all users with the same email.
-	var emailCount int64
-	if err := db.DB.Model(models.User{}).Where(models.User{
-		Email: body.Email,
-	}).Count(&emailCount).Error; err != nil {
+	// Check for a user with the same email.
+	emailTaken, err := h.Repos.Users.EmailTaken(body.Email)
+	if err != nil {
 		return err
 	}
 
 	// If the email already exists in the database, return a conflict error.
-	if emailCount > 0 {
+	if emailTaken {
 		return utils.NewError(http.StatusConflict, "The email address provided has already been registered.", &utils.ErrorDetails{
 			Fields: []utils.ErrorField{
 				{
@@ -54,16 +52,14 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
-	// Count all users with the same email.
-	var usernameCount int64
-	if err := db.DB.Model(models.User{}).Where(models.User{
-		Username: body.Username,
-	}).Count(&usernameCount).Error; err != nil {
+	// Check for a user with the same username.
+	usernameTaken, err := h.Repos.Users.UsernameTaken(body.Username)
+	if err != nil {
 		return err
 	}
 
 	// If the username already exists in the database, return a conflict error.
-	if usernameCount > 0 {
+	if usernameTaken {
 		return utils.NewError(http.StatusConflict, "The username provided has already been registered.", &utils.ErrorDetails{
 			Fields: []utils.ErrorField{
 				{
@@ -75,7 +71,7 @@ func Register(c *fiber.Ctx) error {
 	}
 
 	// Create a password hash
-	hashedPassword, err := utils.CreateHash(body.Password)
+	hashedPassword, err := utils.CreateHash(body.Password, utils.ArgonParamsFromConfig(h.Config))
 	if err != nil {
 		return err
 	}
@@ -89,14 +85,8 @@ func Register(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Generate a verification code
-	code, err := utils.GenerateTOTP(totpSecret, utils.EmailVerification)
-	if err != nil {
-		return err
-	}
-
 	// Define the expiration time
-	exp := time.Now().Add(utils.AuthDuration)
+	exp := time.Now().Add(h.Config.AuthDuration)
 
 	// Create the user and the connection
 	user := models.User{
@@ -123,32 +113,30 @@ func Register(c *fiber.Ctx) error {
 		},
 	}
 
-	// Create the user and the connection
-	if err := db.DB.Create(&user).Error; err != nil {
-		return err
-	}
+	// Create the user and the connection, and queue the verification email along with them so it is never lost.
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.New(tx).Users.Create(&user); err != nil {
+			return err
+		}
 
-	// concurrently send verification email to user, this will not block the response to improve load times
-	go func() {
-		err := mail.VerifyDTO{
+		return mail.VerifyDTO{
 			Defaults: mail.Defaults{
 				Email: user.Email,
 				Name:  user.Username,
 			},
-			Code: code,
-		}.Send()
-		if err != nil {
-			slog.With("email", user.Email).Error("failed to send verification email")
-		}
-	}()
+			ConnectionID: user.Connections[0].ID,
+		}.Queue(tx, h.Mailer)
+	}); err != nil {
+		return err
+	}
 
 	// Set the Authorization cookie
-	utils.SetAuthCookie(c, token, exp)
+	utils.SetAuthCookie(c, h.Config.Domain, token, exp)
 
 	return c.SendStatus(http.StatusCreated)
 }
 
-func Login(c *fiber.Ctx) error {
+func (h *Handler) Login(c *fiber.Ctx) error {
 	// Get the request body and validate it.
 	var body LoginForm
 	if err := utils.ParseAndValidate(c, &body); err != nil {
@@ -156,10 +144,8 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	// Attempt to find the connection by email.
-	var connection models.Connection
-	if err := db.DB.Where(models.Connection{
-		BaseModel: models.BaseModel{ID: models.ProviderEmailType.WithID(body.Email)},
-	}).First(&connection).Error; err != nil {
+	connection, err := h.Repos.Users.FindConnection(models.ProviderEmailType.WithID(body.Email))
+	if err != nil {
 		return err
 	}
 
@@ -178,21 +164,21 @@ func Login(c *fiber.Ctx) error {
 	token := utils.GenerateString(64)
 
 	// Define the expiration time
-	exp := time.Now().Add(utils.AuthDuration)
+	exp := time.Now().Add(h.Config.AuthDuration)
 
 	// Create a new session
-	if err := db.DB.Create(&models.Session{
+	if err := h.Repos.Sessions.Create(&models.Session{
 		BaseModel: models.BaseModel{
 			ID: token,
 		},
 		ConnectionID: connection.ID,
 		ExpiresAt:    exp,
-	}).Error; err != nil {
+	}); err != nil {
 		return err
 	}
 
 	// Set the Authorization cookie
-	utils.SetAuthCookie(c, token, exp)
+	utils.SetAuthCookie(c, h.Config.Domain, token, exp)
 
 	return c.SendStatus(http.StatusCreated)
 }
@@ -0,0 +1,13 @@
+package auth
+
+import "github.com/twibber/core/app"
+
+// Handler serves the authentication endpoints.
+type Handler struct {
+	*app.App
+}
+
+// New creates the authentication handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
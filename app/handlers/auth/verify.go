@@ -3,17 +3,15 @@ package auth
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
 	"github.com/twibber/core/mail"
 	"github.com/twibber/core/utils"
-	"log/slog"
 )
 
 type VerifyForm struct {
 	Code string `json:"code" validate:"required,len=6"`
 }
 
-func Verify(c *fiber.Ctx) error {
+func (h *Handler) Verify(c *fiber.Ctx) error {
 	// Get the user session from the context.
 	var session = c.Locals("session").(models.Session)
 
@@ -27,7 +25,7 @@ func Verify(c *fiber.Ctx) error {
 	}
 
 	// Validate the code provided.
-	if !utils.ValidateTOTP(connection.TOTPVerify, body.Code, utils.EmailVerification) {
+	if !utils.ValidateTOTP(connection.TOTPVerify, body.Code, h.Config.EmailCodeStep) {
 		// Return an error if the code is invalid.
 		return utils.NewError(fiber.StatusBadRequest, "Invalid code provided.", &utils.ErrorDetails{
 			Fields: []utils.ErrorField{
@@ -43,7 +41,7 @@ func Verify(c *fiber.Ctx) error {
 	}
 
 	// Update the connection in the database.
-	if err := db.DB.Updates(&connection).Error; err != nil {
+	if err := h.Repos.Users.UpdateConnection(connection); err != nil {
 		return err
 	}
 
@@ -53,30 +51,22 @@ func Verify(c *fiber.Ctx) error {
 
 // ResendCode is a data structure for resending verification emails,
 // we don't require any input as the user should be logged in to do this.
-func ResendCode(c *fiber.Ctx) error {
+func (h *Handler) ResendCode(c *fiber.Ctx) error {
 	// Get the user session from the context.
 	var session = c.Locals("session").(models.Session)
 
-	// Generate a new code.
-	code, err := utils.GenerateTOTP(session.Connection.TOTPVerify, utils.EmailVerification)
-	if err != nil {
+	// Queue the verification email, it is sent by a background job and retried if the mail server is down.
+	// The code is generated as it is sent, so it is valid however long the mail server was down for.
+	if err := (mail.VerifyDTO{
+		Defaults: mail.Defaults{
+			Email: session.Connection.User.Email,
+			Name:  session.Connection.User.Username,
+		},
+		ConnectionID: session.Connection.ID,
+	}).Queue(h.DB, h.Mailer); err != nil {
 		return err
 	}
 
-	// concurrently send verification email to user
-	go func() {
-		err := mail.VerifyDTO{
-			Defaults: mail.Defaults{
-				Email: session.Connection.User.Email,
-				Name:  session.Connection.User.Username,
-			},
-			Code: code,
-		}.Send()
-		if err != nil {
-			slog.With("email", session.Connection.User.Email).Error("failed to send verification email")
-		}
-	}()
-
 	// Return a successful response.
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -0,0 +1,13 @@
+package health
+
+import "github.com/twibber/core/app"
+
+// Handler serves the health checks.
+type Handler struct {
+	*app.App
+}
+
+// New creates the health check handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/db"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+// pingTimeout is how long the database has to respond before the instance is reported as unhealthy.
+const pingTimeout = 2 * time.Second
+
+// Status is the health of the instance and its dependencies.
+type Status struct {
+	Healthy  bool             `json:"healthy"`
+	Database DatabaseStatus   `json:"database"`
+	Replicas []DatabaseStatus `json:"replicas,omitempty"` // The read replicas, in the order they are configured.
+}
+
+// DatabaseStatus is the health of the database along with the statistics of its connection pool.
+type DatabaseStatus struct {
+	Reachable bool          `json:"reachable"`
+	Latency   time.Duration `json:"latency_ns"`
+	Pool      db.PoolStats  `json:"pool"`
+}
+
+// Live reports that the process is running and serving requests, without checking its dependencies.
+func (h *Handler) Live(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// Ready reports whether the instance can serve traffic, along with the connection pool statistics for metrics.
+// Reads are spread across every replica, so the instance is only ready while the primary and all of the replicas are reachable.
+// Load balancers should stop sending requests to the instance while it responds with 503.
+func (h *Handler) Ready(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), pingTimeout)
+	defer cancel()
+
+	var status Status
+
+	primary, err := checkDatabase(ctx, h.DB, "primary")
+	if err != nil {
+		return err
+	}
+	status.Database = primary
+	status.Healthy = primary.Reachable
+
+	for i, conn := range h.Replicas {
+		replica, err := checkDatabase(ctx, conn, fmt.Sprintf("replica %d", i+1))
+		if err != nil {
+			return err
+		}
+
+		status.Replicas = append(status.Replicas, replica)
+		status.Healthy = status.Healthy && replica.Reachable
+	}
+
+	if !status.Healthy {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(status)
+	}
+
+	return c.JSON(status)
+}
+
+// checkDatabase pings the database and gets the statistics of its connection pool, the name is used for logging.
+func checkDatabase(ctx context.Context, conn *gorm.DB, name string) (DatabaseStatus, error) {
+	var status DatabaseStatus
+
+	start := time.Now()
+	if err := db.Ping(ctx, conn); err != nil {
+		slog.With("database", name, "error", err).Warn("database health check failed")
+	} else {
+		status.Reachable = true
+		status.Latency = time.Since(start)
+	}
+
+	pool, err := db.Stats(conn)
+	if err != nil {
+		return DatabaseStatus{}, err
+	}
+	status.Pool = pool
+
+	return status, nil
+}
@@ -0,0 +1,13 @@
+package lists
+
+import "github.com/twibber/core/app"
+
+// Handler serves the list endpoints.
+type Handler struct {
+	*app.App
+}
+
+// New creates the list handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
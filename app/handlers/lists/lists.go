@@ -0,0 +1,308 @@
+package lists
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+)
+
+// Limits on the number of lists a user can own and the number of accounts in a single list.
+const (
+	maxLists   = 100
+	maxMembers = 500
+)
+
+// ListForm is used to parse the request body for new lists.
+type ListForm struct {
+	Name        string `json:"name" validate:"required,max=64"`
+	Description string `json:"description" validate:"max=256"`
+	Private     bool   `json:"private"`
+}
+
+// UpdateListForm is used to parse the request body for list updates, fields that are not provided are left unchanged.
+type UpdateListForm struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=64"`
+	Description *string `json:"description" validate:"omitempty,max=256"`
+	Private     *bool   `json:"private"`
+}
+
+// ExtendedList represents a list with the number of accounts in it.
+type ExtendedList struct {
+	models.List
+
+	MemberCount int64 `json:"member_count"`
+}
+
+// extendLists extends the lists with their member counts.
+func (h *Handler) extendLists(lists []models.List) ([]ExtendedList, error) {
+	extendedLists := []ExtendedList{}
+	for _, list := range lists {
+		extendedList := ExtendedList{List: list}
+		if err := h.DB.Model(&models.ListMember{}).
+			Where(models.ListMember{ListID: list.ID}).
+			Count(&extendedList.MemberCount).Error; err != nil {
+			return nil, err
+		}
+
+		extendedLists = append(extendedLists, extendedList)
+	}
+
+	return extendedLists, nil
+}
+
+// findOwnedList gets a list by its ID, only the owner of a list can change it.
+func (h *Handler) findOwnedList(listID, ownerID string) (models.List, error) {
+	var list models.List
+	err := h.DB.Where(models.List{
+		BaseModel: models.BaseModel{ID: listID},
+		OwnerID:   ownerID,
+	}).First(&list).Error
+	return list, err
+}
+
+// ListOwnLists handles the retrieval of the current user's lists, including their private lists.
+func (h *Handler) ListOwnLists(c *fiber.Ctx) error {
+	// Get the current session of the owner.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	var lists []models.List
+	if err := h.DB.
+		Where(models.List{OwnerID: userID}).
+		Order("name asc").
+		Find(&lists).Error; err != nil {
+		return err
+	}
+
+	extendedLists, err := h.extendLists(lists)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(extendedLists)
+}
+
+// CreateList handles the creation of new lists.
+func (h *Handler) CreateList(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body ListForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the current session of the owner.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Check the user has not reached the limit of lists.
+	var count int64
+	if err := h.DB.Model(&models.List{}).
+		Where(models.List{OwnerID: userID}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count >= maxLists {
+		return utils.NewError(fiber.StatusForbidden, "You have reached the maximum number of lists.", nil)
+	}
+
+	list := models.List{
+		OwnerID:     userID,
+		Name:        body.Name,
+		Description: body.Description,
+		Private:     body.Private,
+	}
+	if err := h.DB.Create(&list).Error; err != nil {
+		return err
+	}
+
+	// Return the created list.
+	return c.JSON(list)
+}
+
+// GetList handles the retrieval of a single list by its ID.
+func (h *Handler) GetList(c *fiber.Ctx) error {
+	// Get the id of the current user.
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	var list models.List
+	if err := h.DB.
+		Preload("Owner", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("Email") // Omit the email of the owner for privacy reasons.
+		}).
+		Scopes(models.ListVisibleTo(userID)).
+		Where(models.List{BaseModel: models.BaseModel{ID: c.Params("list")}}).
+		First(&list).Error; err != nil {
+		return err
+	}
+
+	extendedLists, err := h.extendLists([]models.List{list})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(extendedLists[0])
+}
+
+// UpdateList handles updating the name, description and privacy of a list.
+func (h *Handler) UpdateList(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body UpdateListForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the list, only its owner can update it.
+	list, err := h.findOwnedList(c.Params("list"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Only update the fields that were provided.
+	updates := make(map[string]interface{})
+	if body.Name != nil {
+		updates["name"] = *body.Name
+	}
+	if body.Description != nil {
+		updates["description"] = *body.Description
+	}
+	if body.Private != nil {
+		updates["private"] = *body.Private
+	}
+
+	if len(updates) > 0 {
+		if err := h.DB.Model(&list).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(list)
+}
+
+// DeleteList handles the deletion of a list along with its members.
+func (h *Handler) DeleteList(c *fiber.Ctx) error {
+	// Get the list, only its owner can delete it.
+	list, err := h.findOwnedList(c.Params("list"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Delete(&list).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ListMembers handles the retrieval of the accounts in a list, most recently added first.
+func (h *Handler) ListMembers(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Get the id of the current user.
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Get the list, the members are hidden along with it.
+	var list models.List
+	if err := h.DB.
+		Scopes(models.ListVisibleTo(userID)).
+		Where(models.List{BaseModel: models.BaseModel{ID: c.Params("list")}}).
+		First(&list).Error; err != nil {
+		return err
+	}
+
+	members := []models.ListMember{}
+	if err := h.DB.
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("Email") // Omit the email of the members for privacy reasons.
+		}).
+		Where(models.ListMember{ListID: list.ID}).
+		Order("created_at desc").
+		Scopes(page.Paginate).
+		Find(&members).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(members)
+}
+
+// AddMember handles adding an account to a list by its username.
+func (h *Handler) AddMember(c *fiber.Ctx) error {
+	// Get the current session of the owner.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the list, only its owner can add accounts to it.
+	list, err := h.findOwnedList(c.Params("list"), userID)
+	if err != nil {
+		return err
+	}
+
+	// Get the user being added by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	// Users cannot be added to lists while either user has blocked the other.
+	var blocks int64
+	if err := h.DB.Model(&models.Block{}).
+		Scopes(models.BlockBetween(userID, user.ID)).
+		Count(&blocks).Error; err != nil {
+		return err
+	}
+
+	if blocks > 0 {
+		return utils.NewError(fiber.StatusForbidden, "You cannot add this user to a list.", nil)
+	}
+
+	// Check the list has not reached the limit of members.
+	var count int64
+	if err := h.DB.Model(&models.ListMember{}).
+		Where(models.ListMember{ListID: list.ID}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count >= maxMembers {
+		return utils.NewError(fiber.StatusForbidden, "This list has reached the maximum number of members.", nil)
+	}
+
+	if err := h.DB.Create(&models.ListMember{
+		ListID: list.ID,
+		UserID: user.ID,
+	}).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.NewError(fiber.StatusConflict, "This user is already in the list.", nil)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// RemoveMember handles removing an account from a list by its username.
+func (h *Handler) RemoveMember(c *fiber.Ctx) error {
+	// Get the list, only its owner can remove accounts from it.
+	list, err := h.findOwnedList(c.Params("list"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Get the user being removed by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Where(models.ListMember{
+		ListID: list.ID,
+		UserID: user.ID,
+	}).Delete(&models.ListMember{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,13 @@
+package live
+
+import "github.com/twibber/core/app"
+
+// Handler serves the WebSocket connections.
+type Handler struct {
+	*app.App
+}
+
+// New creates the WebSocket handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
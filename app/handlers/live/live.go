@@ -0,0 +1,357 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/pubsub"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	sendBufferSize   = 64               // Messages queued per client before it is considered too slow and disconnected.
+	maxMessageSize   = 4096             // Largest message accepted from a client in bytes.
+	maxSubscriptions = 50               // Threads a single connection can subscribe to at once.
+	writeTimeout     = 10 * time.Second // How long a single write to the client can take.
+	pongTimeout      = 60 * time.Second // How long the client has to respond to a ping before it is disconnected.
+	pingInterval     = 30 * time.Second // How often the client is pinged, must be less than pongTimeout.
+	typingInterval   = 3 * time.Second  // How often a client can signal that it is replying in a thread.
+)
+
+// Upgrade only lets WebSocket upgrade requests through, and attaches the ID of the current user if there is a session.
+func (h *Handler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	// The connection is authenticated with the existing cookie, but authentication is optional.
+	c.Locals("user_id", utils.GetUserID(c, h.Repos.Sessions))
+
+	return c.Next()
+}
+
+// Live handles a single WebSocket connection, see the package documentation for the protocol.
+func (h *Handler) Live(conn *websocket.Conn) {
+	userID, _ := conn.Locals("user_id").(string)
+
+	cl := &client{
+		db:            h.DB,
+		broker:        h.Broker,
+		conn:          conn,
+		userID:        userID,
+		send:          make(chan Message, sendBufferSize),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]*subscription),
+		typing:        make(map[string]time.Time),
+	}
+
+	// Writes happen in their own goroutine, so a slow client never blocks the thread updates.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		cl.write()
+	}()
+
+	cl.read()
+
+	// Stop the thread updates and wait for the final close message to be written.
+	cl.close(websocket.CloseNormalClosure, "")
+	for _, sub := range cl.subscriptions {
+		sub.close()
+	}
+	<-written
+}
+
+// client is the state of a single connection.
+type client struct {
+	db     *gorm.DB
+	broker pubsub.Broker
+
+	conn   *websocket.Conn
+	userID string
+
+	send chan Message
+	done chan struct{}
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+
+	// Only accessed from the read loop.
+	subscriptions map[string]*subscription
+	typing        map[string]time.Time
+}
+
+// subscription is a client's subscription to a single thread.
+type subscription struct {
+	*pubsub.Subscription
+
+	unsubscribed atomic.Bool
+}
+
+// close stops the subscription without treating it as the client falling behind.
+func (s *subscription) close() {
+	s.unsubscribed.Store(true)
+	s.Close()
+}
+
+// close disconnects the client with the close code and reason, only the first call has any effect.
+func (cl *client) close(code int, reason string) {
+	cl.closeOnce.Do(func() {
+		cl.closeCode = code
+		cl.closeReason = reason
+		close(cl.done)
+	})
+}
+
+// enqueue queues the message to be written, disconnecting the client if its queue is full.
+func (cl *client) enqueue(message Message) bool {
+	select {
+	case <-cl.done:
+		return false
+	default:
+	}
+
+	select {
+	case cl.send <- message:
+		return true
+	default:
+		cl.close(websocket.CloseTryAgainLater, "client too slow")
+		return false
+	}
+}
+
+// error queues an error message for the client.
+func (cl *client) error(postID, message string) {
+	cl.enqueue(Message{
+		Type:    MessageError,
+		PostID:  postID,
+		Message: message,
+	})
+}
+
+// write writes the queued messages and pings to the client until it is closed.
+func (cl *client) write() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	// Closing the connection also stops the read loop.
+	defer cl.conn.Close()
+
+	for {
+		select {
+		case message := <-cl.send:
+			_ = cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := cl.conn.WriteJSON(message); err != nil {
+				cl.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := cl.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				cl.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-cl.done:
+			if cl.closeCode != websocket.CloseAbnormalClosure {
+				_ = cl.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(cl.closeCode, cl.closeReason),
+					time.Now().Add(writeTimeout))
+			}
+			return
+		}
+	}
+}
+
+// read handles the messages sent by the client until the connection is closed.
+func (cl *client) read() {
+	cl.conn.SetReadLimit(maxMessageSize)
+	_ = cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	cl.conn.SetPongHandler(func(string) error {
+		return cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var message Message
+		if err := cl.conn.ReadJSON(&message); err != nil {
+			// Malformed messages are reported, anything else means the connection has gone.
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				cl.error("", "The message could not be parsed.")
+				continue
+			}
+			return
+		}
+
+		// Any message from the client shows it is still alive.
+		_ = cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+
+		switch message.Type {
+		case MessageSubscribe:
+			cl.subscribe(message.PostID)
+		case MessageUnsubscribe:
+			cl.unsubscribe(message.PostID)
+		case MessageTyping:
+			cl.signalTyping(message.PostID)
+		case MessagePing:
+			cl.enqueue(Message{Type: MessagePong})
+		default:
+			cl.error(message.PostID, "The message type is not supported.")
+		}
+	}
+}
+
+// subscribe subscribes the client to the updates of a post it can see.
+func (cl *client) subscribe(postID string) {
+	if postID == "" {
+		cl.error(postID, "A post ID is required.")
+		return
+	}
+
+	if _, ok := cl.subscriptions[postID]; ok {
+		cl.enqueue(Message{Type: MessageSubscribed, PostID: postID})
+		return
+	}
+
+	if len(cl.subscriptions) >= maxSubscriptions {
+		cl.error(postID, "You are subscribed to too many threads.")
+		return
+	}
+
+	// Only threads visible to the user can be subscribed to.
+	var count int64
+	if err := cl.db.Model(&models.Post{}).
+		Scopes(models.VisibleTo(cl.userID)).
+		Where("posts.id = ?", postID).
+		Count(&count).Error; err != nil {
+		slog.With("post", postID, "error", err).Error("failed to check the visibility of a thread")
+		cl.error(postID, "An internal server error occurred.")
+		return
+	}
+
+	if count == 0 {
+		cl.error(postID, "The requested resource could not be found.")
+		return
+	}
+
+	sub := &subscription{Subscription: cl.broker.Subscribe([]string{pubsub.ThreadTopic(postID)}, 0)}
+	cl.subscriptions[postID] = sub
+	go cl.forward(postID, sub)
+
+	cl.enqueue(Message{Type: MessageSubscribed, PostID: postID})
+}
+
+// unsubscribe stops the updates of a post.
+func (cl *client) unsubscribe(postID string) {
+	if sub, ok := cl.subscriptions[postID]; ok {
+		sub.close()
+		delete(cl.subscriptions, postID)
+		delete(cl.typing, postID)
+	}
+
+	cl.enqueue(Message{Type: MessageUnsubscribed, PostID: postID})
+}
+
+// signalTyping lets the subscribers of a thread know the user is replying to it.
+func (cl *client) signalTyping(postID string) {
+	if cl.userID == "" {
+		cl.error(postID, "You must be logged in to perform this action.")
+		return
+	}
+
+	if _, ok := cl.subscriptions[postID]; !ok {
+		cl.error(postID, "You must be subscribed to the thread to perform this action.")
+		return
+	}
+
+	// Typing is repeated while the user writes, so only signal it every so often.
+	if last, ok := cl.typing[postID]; ok && time.Since(last) < typingInterval {
+		return
+	}
+	cl.typing[postID] = time.Now()
+
+	if err := cl.broker.Signal(pubsub.ThreadTopic(postID), pubsub.EventPresence, pubsub.PresenceEvent{
+		PostID: postID,
+		UserID: cl.userID,
+		State:  PresenceStateReplying,
+	}); err != nil {
+		slog.With("post", postID, "error", err).Error("failed to signal presence")
+	}
+}
+
+// forward passes the updates of a thread to the client, skipping anything the user cannot see.
+func (cl *client) forward(postID string, sub *subscription) {
+	for event := range sub.Events {
+		if !cl.allowed(event) {
+			continue
+		}
+
+		if !cl.enqueue(Message{
+			Type:   event.Type,
+			PostID: postID,
+			Data:   event.Data,
+		}) {
+			return
+		}
+	}
+
+	// The broker dropped the subscription because the client fell behind.
+	if !sub.unsubscribed.Load() {
+		cl.close(websocket.CloseTryAgainLater, "client too slow")
+	}
+}
+
+// allowed checks whether the user can see the update.
+func (cl *client) allowed(event pubsub.Event) bool {
+	switch event.Type {
+	case pubsub.EventReply:
+		// Replies can be more restricted than the thread they are in.
+		var data pubsub.PostEvent
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return false
+		}
+
+		var count int64
+		if err := cl.db.Model(&models.Post{}).
+			Scopes(models.VisibleTo(cl.userID)).
+			Where("posts.id = ?", data.PostID).
+			Count(&count).Error; err != nil {
+			slog.With("post", data.PostID, "error", err).Error("failed to check the visibility of a reply")
+			return false
+		}
+
+		return count > 0
+	case pubsub.EventPresence:
+		var data pubsub.PresenceEvent
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return false
+		}
+
+		// Users never see their own presence, and never see the presence of users they have blocked or been blocked by.
+		if data.UserID == cl.userID {
+			return false
+		}
+		if cl.userID == "" {
+			return true
+		}
+
+		var count int64
+		if err := cl.db.Model(&models.Block{}).
+			Scopes(models.BlockBetween(cl.userID, data.UserID)).
+			Count(&count).Error; err != nil {
+			slog.With("user", data.UserID, "error", err).Error("failed to check blocks for presence")
+			return false
+		}
+
+		return count == 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,65 @@
+// Package live serves the WebSocket API for live thread updates and presence.
+//
+// Every message in either direction is a JSON object with a type, the protocol is as follows.
+//
+// Messages sent by the client:
+//
+//	{"type": "subscribe", "post_id": "<id>"}    Start receiving the updates of a post and its direct replies.
+//	{"type": "unsubscribe", "post_id": "<id>"}  Stop receiving the updates of a post.
+//	{"type": "typing", "post_id": "<id>"}       Let the thread know the user is replying, requires a session and a subscription.
+//	{"type": "ping"}                            Check the connection is alive, the server responds with a pong.
+//
+// Messages sent by the server:
+//
+//	{"type": "subscribed", "post_id": "<id>"}
+//	{"type": "unsubscribed", "post_id": "<id>"}
+//	{"type": "reply", "post_id": "<id>", "data": {"post_id": "<reply id>", "author_id": "<id>"}}
+//	{"type": "likes", "post_id": "<id>", "data": {"post_id": "<id>", "likes": 3}}
+//	{"type": "presence", "post_id": "<id>", "data": {"post_id": "<id>", "user_id": "<id>", "state": "replying"}}
+//	{"type": "pong"}
+//	{"type": "error", "post_id": "<id>", "message": "<reason>"}
+//
+// Likes events are sent for the subscribed post and each of its direct replies. Only identifiers are sent,
+// clients fetch the posts themselves so every visibility rule is still applied.
+//
+// Presence is ephemeral and never replayed, clients send typing at most every few seconds while the user is
+// writing and should treat a user as replying until PresenceTimeout has passed without another presence event.
+//
+// Clients that cannot keep up with their updates are disconnected with the close code 1013 (try again later),
+// they should reconnect and fetch the threads again to catch up.
+package live
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Message types sent by the client.
+const (
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessageTyping      = "typing"
+	MessagePing        = "ping"
+)
+
+// Message types sent by the server, alongside the pubsub event types of the thread topics.
+const (
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessagePong         = "pong"
+	MessageError        = "error"
+)
+
+// PresenceStateReplying is the presence state of a user writing a reply.
+const PresenceStateReplying = "replying"
+
+// PresenceTimeout is how long a presence signal lasts without being repeated.
+const PresenceTimeout = 6 * time.Second
+
+// Message is a single message of the protocol in either direction.
+type Message struct {
+	Type    string          `json:"type"`
+	PostID  string          `json:"post_id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
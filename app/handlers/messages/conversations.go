@@ -0,0 +1,280 @@
+package messages
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ConversationForm is used to parse the request body for starting a conversation.
+// A conversation with a single other user is a one to one conversation, and continues the existing one if there is one.
+// Group conversations have at most ten members, including the user who started it.
+type ConversationForm struct {
+	Usernames []string `json:"usernames" validate:"required,min=1,max=9,unique,dive,required"`
+	Name      string   `json:"name" validate:"omitempty,max=64"` // Only used for group conversations.
+	Content   string   `json:"content" validate:"required,max=1000"`
+}
+
+// ExtendedConversation represents a conversation with the current user's unread count and the latest message they can see.
+type ExtendedConversation struct {
+	models.Conversation
+
+	Unread      int64           `json:"unread"`       // Messages from other members since the current user last read the conversation.
+	LastMessage *models.Message `json:"last_message"` // The most recent message visible to the current user.
+}
+
+// extendConversation extends the conversation for the member.
+func (h *Handler) extendConversation(conversation models.Conversation, member models.ConversationMember) (ExtendedConversation, error) {
+	extendedConversation := ExtendedConversation{Conversation: conversation}
+
+	// Count the messages from other members since the conversation was last read.
+	tx := h.DB.Model(&models.Message{}).
+		Scopes(models.MessagesVisibleTo(member)).
+		Where("messages.sender_id <> ?", member.UserID)
+	if member.LastReadAt != nil {
+		tx = tx.Where("messages.created_at > ?", *member.LastReadAt)
+	}
+	if err := tx.Count(&extendedConversation.Unread).Error; err != nil {
+		return extendedConversation, err
+	}
+
+	// Get the latest message the member can see, if there is one.
+	var messages []models.Message
+	if err := h.DB.
+		Scopes(models.MessagesVisibleTo(member)).
+		Order("messages.created_at desc").
+		Limit(1).
+		Find(&messages).Error; err != nil {
+		return extendedConversation, err
+	}
+
+	if len(messages) > 0 {
+		extendedConversation.LastMessage = &messages[0]
+	}
+
+	return extendedConversation, nil
+}
+
+// preloadMembers preloads the members of a conversation along with their users.
+func preloadMembers(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Members.User", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("Email") // Omit the email of the members for privacy reasons.
+	})
+}
+
+// findMember gets the current user's membership of a conversation, users who are not members cannot see it at all.
+func (h *Handler) findMember(conversationID, userID string) (models.ConversationMember, error) {
+	var member models.ConversationMember
+	err := h.DB.Where(models.ConversationMember{
+		ConversationID: conversationID,
+		UserID:         userID,
+	}).First(&member).Error
+	return member, err
+}
+
+// CreateConversation handles starting a conversation with one or more users, along with its first message.
+func (h *Handler) CreateConversation(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body ConversationForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the current session of the user starting the conversation.
+	senderID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the users being messaged.
+	var recipients []models.User
+	if err := h.DB.
+		Where("username IN ?", body.Usernames).
+		Find(&recipients).Error; err != nil {
+		return err
+	}
+
+	if len(recipients) != len(body.Usernames) {
+		return utils.NewError(fiber.StatusNotFound, "One or more of the users could not be found.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "usernames",
+					Errors: []string{"One or more of the users could not be found."},
+				},
+			},
+		})
+	}
+
+	for _, recipient := range recipients {
+		if recipient.ID == senderID {
+			return utils.NewError(fiber.StatusBadRequest, "You cannot start a conversation with yourself.", nil)
+		}
+	}
+
+	// Make sure every user accepts messages from the sender.
+	if err := h.canMessage(senderID, recipients); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var conversation models.Conversation
+	var message models.Message
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		memberIDs := []string{senderID}
+		for _, recipient := range recipients {
+			memberIDs = append(memberIDs, recipient.ID)
+		}
+
+		if len(recipients) == 1 {
+			// Continue the existing one to one conversation, or start it if there is none.
+			key := models.DirectKey(senderID, recipients[0].ID)
+			if err := tx.
+				Where(models.Conversation{DirectKey: &key}).
+				Attrs(models.Conversation{LastMessageAt: now}).
+				FirstOrCreate(&conversation).Error; err != nil {
+				return err
+			}
+		} else {
+			conversation = models.Conversation{
+				Name:          body.Name,
+				LastMessageAt: now,
+			}
+			if err := tx.Create(&conversation).Error; err != nil {
+				return err
+			}
+		}
+
+		// Add the members, anyone who left a one to one conversation rejoins it.
+		if err := join(tx, conversation.ID, memberIDs, now); err != nil {
+			return err
+		}
+
+		var err error
+		message, err = send(tx, conversation.ID, senderID, body.Content)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Let the other members know about the message.
+	h.announce(message)
+
+	// Return the conversation with its members.
+	if err := h.DB.Scopes(preloadMembers).
+		Where(models.Conversation{BaseModel: models.BaseModel{ID: conversation.ID}}).
+		First(&conversation).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(conversation)
+}
+
+// ListConversations handles the retrieval of the current user's conversations, most recently active first.
+func (h *Handler) ListConversations(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Conversations are private, so they are only ever listed for the current user.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	var conversations []models.Conversation
+	if err := h.DB.
+		Scopes(preloadMembers).
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = conversations.id AND conversation_members.user_id = ?", userID).
+		Order("conversations.last_message_at desc").
+		Scopes(page.Paginate).
+		Find(&conversations).Error; err != nil {
+		return err
+	}
+
+	extendedConversations := []ExtendedConversation{}
+	for _, conversation := range conversations {
+		for _, member := range conversation.Members {
+			if member.UserID != userID {
+				continue
+			}
+
+			extendedConversation, err := h.extendConversation(conversation, member)
+			if err != nil {
+				return err
+			}
+
+			extendedConversations = append(extendedConversations, extendedConversation)
+		}
+	}
+
+	return c.JSON(extendedConversations)
+}
+
+// GetConversation handles the retrieval of a single conversation by its ID.
+func (h *Handler) GetConversation(c *fiber.Ctx) error {
+	// Get the current user's membership, only members can see the conversation.
+	member, err := h.findMember(c.Params("conversation"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	var conversation models.Conversation
+	if err := h.DB.Scopes(preloadMembers).
+		Where(models.Conversation{BaseModel: models.BaseModel{ID: member.ConversationID}}).
+		First(&conversation).Error; err != nil {
+		return err
+	}
+
+	extendedConversation, err := h.extendConversation(conversation, member)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(extendedConversation)
+}
+
+// ReadConversation handles marking every message in a conversation as read for the current user.
+func (h *Handler) ReadConversation(c *fiber.Ctx) error {
+	// Get the current user's membership of the conversation.
+	member, err := h.findMember(c.Params("conversation"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Model(&member).Update("last_read_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// LeaveConversation handles the current user leaving a conversation, it is deleted once every member has left.
+func (h *Handler) LeaveConversation(c *fiber.Ctx) error {
+	// Get the current user's membership of the conversation.
+	member, err := h.findMember(c.Params("conversation"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+
+		// Delete the conversation along with its messages when nobody is left in it.
+		var remaining int64
+		if err := tx.Model(&models.ConversationMember{}).
+			Where(models.ConversationMember{ConversationID: member.ConversationID}).
+			Count(&remaining).Error; err != nil {
+			return err
+		}
+
+		if remaining > 0 {
+			return nil
+		}
+
+		return tx.Delete(&models.Conversation{BaseModel: models.BaseModel{ID: member.ConversationID}}).Error
+	}); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
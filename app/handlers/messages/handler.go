@@ -0,0 +1,13 @@
+package messages
+
+import "github.com/twibber/core/app"
+
+// Handler serves the direct message endpoints.
+type Handler struct {
+	*app.App
+}
+
+// New creates the direct message handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
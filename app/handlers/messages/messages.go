@@ -0,0 +1,239 @@
+package messages
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/pubsub"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// MessageForm is used to parse the request body for sending a message.
+type MessageForm struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+// canMessage checks that every recipient accepts messages from the sender.
+// Nobody can message a user while either has blocked the other, and users who only accept messages from their
+// followers can only be messaged by them.
+func (h *Handler) canMessage(senderID string, recipients []models.User) error {
+	for _, recipient := range recipients {
+		var blocks int64
+		if err := h.DB.Model(&models.Block{}).
+			Scopes(models.BlockBetween(senderID, recipient.ID)).
+			Count(&blocks).Error; err != nil {
+			return err
+		}
+
+		allowed := blocks == 0
+		if allowed && recipient.DMFollowersOnly {
+			var follows int64
+			if err := h.DB.Model(&models.Follow{}).Where(models.Follow{
+				FollowerID:  senderID,
+				FollowingID: recipient.ID,
+			}).Count(&follows).Error; err != nil {
+				return err
+			}
+
+			allowed = follows > 0
+		}
+
+		if !allowed {
+			return utils.NewError(fiber.StatusForbidden, "You cannot message one or more of these users.", nil)
+		}
+	}
+
+	return nil
+}
+
+// join adds the users to the conversation, users who are already members are left as they are.
+func join(tx *gorm.DB, conversationID string, userIDs []string, joinedAt time.Time) error {
+	var members []models.ConversationMember
+	for _, userID := range userIDs {
+		members = append(members, models.ConversationMember{
+			ConversationID: conversationID,
+			UserID:         userID,
+			JoinedAt:       joinedAt,
+		})
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+// send creates the message and moves the conversation to the top, the sender has read everything up to their own message.
+func send(tx *gorm.DB, conversationID, senderID, content string) (models.Message, error) {
+	message := models.Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Content:        content,
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return message, err
+	}
+
+	if err := tx.Model(&models.Conversation{}).
+		Where("id = ?", conversationID).
+		Update("last_message_at", message.CreatedAt).Error; err != nil {
+		return message, err
+	}
+
+	return message, tx.Model(&models.ConversationMember{}).
+		Where(models.ConversationMember{ConversationID: conversationID, UserID: senderID}).
+		Update("last_read_at", message.CreatedAt).Error
+}
+
+// announce pushes the message to the streams of the other members of its conversation.
+func (h *Handler) announce(message models.Message) {
+	var memberIDs []string
+	if err := h.DB.Model(&models.ConversationMember{}).
+		Where(models.ConversationMember{ConversationID: message.ConversationID}).
+		Where("user_id <> ?", message.SenderID).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		slog.With("message", message.ID, "error", err).Error("failed to find the members of a conversation")
+		return
+	}
+
+	for _, memberID := range memberIDs {
+		if err := h.Broker.Publish(pubsub.UserTopic(memberID), pubsub.EventMessage, pubsub.MessageEvent{
+			ConversationID: message.ConversationID,
+			MessageID:      message.ID,
+			SenderID:       message.SenderID,
+		}); err != nil {
+			slog.With("message", message.ID, "recipient", memberID, "error", err).Error("failed to publish message event")
+		}
+	}
+}
+
+// ListMessages handles the retrieval of the messages in a conversation, most recent first.
+func (h *Handler) ListMessages(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Get the current user's membership, only members can see the messages.
+	member, err := h.findMember(c.Params("conversation"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	messages := []models.Message{}
+	if err := h.DB.
+		Preload("Sender", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("Email") // Omit the email of the sender for privacy reasons.
+		}).
+		Scopes(models.MessagesVisibleTo(member), page.Paginate).
+		Order("messages.created_at desc").
+		Find(&messages).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(messages)
+}
+
+// SendMessage handles sending a message in a conversation.
+func (h *Handler) SendMessage(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body MessageForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the current session of the sender.
+	senderID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the sender's membership, only members can send messages.
+	member, err := h.findMember(c.Params("conversation"), senderID)
+	if err != nil {
+		return err
+	}
+
+	var conversation models.Conversation
+	if err := h.DB.
+		Preload("Members").
+		Where(models.Conversation{BaseModel: models.BaseModel{ID: member.ConversationID}}).
+		First(&conversation).Error; err != nil {
+		return err
+	}
+
+	// The recipients are the other members, in a one to one conversation the other user rejoins if they left.
+	var recipientIDs []string
+	if conversation.Direct() {
+		for _, userID := range strings.Split(*conversation.DirectKey, ":") {
+			if userID != senderID {
+				recipientIDs = append(recipientIDs, userID)
+			}
+		}
+	} else {
+		for _, other := range conversation.Members {
+			if other.UserID != senderID {
+				recipientIDs = append(recipientIDs, other.UserID)
+			}
+		}
+	}
+
+	// Make sure every recipient still accepts messages from the sender.
+	var recipients []models.User
+	if len(recipientIDs) > 0 {
+		if err := h.DB.Where("id IN ?", recipientIDs).Find(&recipients).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := h.canMessage(senderID, recipients); err != nil {
+		return err
+	}
+
+	var message models.Message
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if conversation.Direct() {
+			if err := join(tx, conversation.ID, recipientIDs, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		message, err = send(tx, conversation.ID, senderID, body.Content)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Let the other members know about the message.
+	h.announce(message)
+
+	// Return the sent message.
+	return c.JSON(message)
+}
+
+// DeleteMessage handles deleting a message for the current user only, the other members can still see it.
+func (h *Handler) DeleteMessage(c *fiber.Ctx) error {
+	// Get the current user's membership of the conversation.
+	member, err := h.findMember(c.Params("conversation"), c.Locals("session").(models.Session).Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Get the message by its ID, it must be visible to the member.
+	var message models.Message
+	if err := h.DB.
+		Scopes(models.MessagesVisibleTo(member)).
+		Where("messages.id = ?", c.Params("message")).
+		First(&message).Error; err != nil {
+		return err
+	}
+
+	if err := h.DB.Create(&models.MessageDeletion{
+		MessageID: message.ID,
+		UserID:    member.UserID,
+	}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
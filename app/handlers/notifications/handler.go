@@ -0,0 +1,13 @@
+package notifications
+
+import "github.com/twibber/core/app"
+
+// Handler serves the notification endpoints.
+type Handler struct {
+	*app.App
+}
+
+// New creates the notification handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
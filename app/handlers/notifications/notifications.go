@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ExtendedNotification represents a notification group with a human-readable summary.
+type ExtendedNotification struct {
+	models.Notification
+
+	Unread  bool   `json:"unread"`  // Whether the notification has not been read yet.
+	Summary string `json:"summary"` // Summary of the group, such as "alice and 4 others liked your post".
+}
+
+// NotificationList is the response for listing notifications.
+type NotificationList struct {
+	Unread        int64                  `json:"unread"` // Total unread notifications, regardless of the page.
+	Notifications []ExtendedNotification `json:"notifications"`
+}
+
+// summaries map each notification type to the action shown in its summary.
+var summaries = map[models.NotificationType]string{
+	models.NotificationLike:          "liked your post",
+	models.NotificationReply:         "replied to your post",
+	models.NotificationFollow:        "followed you",
+	models.NotificationFollowRequest: "requested to follow you",
+}
+
+// summarise builds the summary of a notification group from its latest actor and the number of actors.
+func summarise(notification models.Notification) string {
+	name := "Someone"
+	if notification.LatestActor != nil {
+		name = notification.LatestActor.DisplayName
+		if name == "" {
+			name = notification.LatestActor.Username
+		}
+	}
+
+	switch others := notification.ActorCount - 1; {
+	case others == 1:
+		name += " and 1 other"
+	case others > 1:
+		name += fmt.Sprintf(" and %d others", others)
+	}
+
+	return name + " " + summaries[notification.Type]
+}
+
+// ListNotifications returns the current user's notifications, most recent first, along with the unread count.
+func (h *Handler) ListNotifications(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Get the current session.
+	session := c.Locals("session").(models.Session)
+	userID := session.Connection.UserID
+
+	var notifications []models.Notification
+	if err := h.DB.
+		Preload("LatestActor", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("Email") // Omit the email of the actor for privacy reasons.
+		}).
+		Preload("Post").
+		Where(models.Notification{RecipientID: userID}).
+		Order("latest_at desc").
+		Scopes(page.Paginate).
+		Find(&notifications).Error; err != nil {
+		return err
+	}
+
+	// Count every unread notification, not just the ones on this page.
+	var unread int64
+	if err := h.DB.Model(&models.Notification{}).
+		Where(models.Notification{RecipientID: userID}).
+		Where("read_at IS NULL").
+		Count(&unread).Error; err != nil {
+		return err
+	}
+
+	list := NotificationList{
+		Unread:        unread,
+		Notifications: []ExtendedNotification{},
+	}
+	for _, notification := range notifications {
+		list.Notifications = append(list.Notifications, ExtendedNotification{
+			Notification: notification,
+			Unread:       notification.ReadAt == nil,
+			Summary:      summarise(notification),
+		})
+	}
+
+	return c.JSON(list)
+}
+
+// ReadForm is used to parse the request body for marking notifications as read.
+type ReadForm struct {
+	// Cursor is the ID of the newest notification that has been seen, every notification up to it is marked as read.
+	// When empty, every notification is marked as read.
+	Cursor string `json:"cursor"`
+}
+
+// ReadNotifications marks the current user's notifications as read up to and including the cursor.
+func (h *Handler) ReadNotifications(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body ReadForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the current session.
+	session := c.Locals("session").(models.Session)
+	userID := session.Connection.UserID
+
+	tx := h.DB.Model(&models.Notification{}).
+		Where(models.Notification{RecipientID: userID}).
+		Where("read_at IS NULL")
+
+	// Only mark the notifications up to the cursor, so anything that arrived since is left unread.
+	if body.Cursor != "" {
+		var cursor models.Notification
+		if err := h.DB.Where(models.Notification{
+			BaseModel:   models.BaseModel{ID: body.Cursor},
+			RecipientID: userID,
+		}).First(&cursor).Error; err != nil {
+			return err
+		}
+
+		tx = tx.Where("latest_at <= ?", cursor.LatestAt)
+	}
+
+	if err := tx.Update("read_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
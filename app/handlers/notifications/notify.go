@@ -0,0 +1,167 @@
+package notifications
+
+import (
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/pubsub"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+// Event describes something that happened which the recipient should be notified about.
+type Event struct {
+	Type        models.NotificationType
+	RecipientID string  // User being notified.
+	ActorID     string  // User that caused the event.
+	PostID      *string // Post the event happened on, events are grouped on it when set.
+	Content     string  // Content the recipient's notification mute filters are matched against, if any.
+}
+
+// groupKey returns the key events are grouped on, events of the same type on the same post or user share a group.
+func (e Event) groupKey() string {
+	if e.PostID != nil {
+		return *e.PostID
+	}
+	return e.RecipientID
+}
+
+// Notifier records notifications and pushes them to the recipient's open streams.
+type Notifier struct {
+	db     *gorm.DB
+	broker pubsub.Broker
+}
+
+// NewNotifier creates a notifier that records notifications in the database and publishes them to the broker.
+func NewNotifier(db *gorm.DB, broker pubsub.Broker) *Notifier {
+	return &Notifier{db: db, broker: broker}
+}
+
+// Notify records the event as a notification for its recipient.
+// Notifications are a side effect of the request that caused them, so failures are logged rather than returned.
+func (n *Notifier) Notify(event Event) {
+	if err := n.notify(event); err != nil {
+		slog.With(
+			"type", event.Type,
+			"recipient", event.RecipientID,
+			"actor", event.ActorID,
+			"error", err,
+		).Error("failed to create notification")
+	}
+}
+
+// notify adds the event to the recipient's unread group for the target, creating the group if there is none.
+func (n *Notifier) notify(event Event) error {
+	// Users are never notified about their own actions.
+	if event.RecipientID == event.ActorID {
+		return nil
+	}
+
+	// Skip the notification if either user has blocked the other, or the recipient has muted the actor.
+	var blocks, mutes int64
+	if err := n.db.Model(&models.Block{}).
+		Scopes(models.BlockBetween(event.RecipientID, event.ActorID)).
+		Count(&blocks).Error; err != nil {
+		return err
+	}
+	if err := n.db.Model(&models.Mute{}).Where(models.Mute{
+		MuterID: event.RecipientID,
+		MutedID: event.ActorID,
+	}).Count(&mutes).Error; err != nil {
+		return err
+	}
+	if blocks > 0 || mutes > 0 {
+		return nil
+	}
+
+	// Skip the notification if it matches one of the recipient's notification filters that hides.
+	if event.Content != "" {
+		var filters []models.MuteFilter
+		if err := n.db.
+			Scopes(models.ActiveFilters(event.RecipientID, models.FilterScopeNotifications)).
+			Where(models.MuteFilter{Action: models.FilterActionHide}).
+			Find(&filters).Error; err != nil {
+			return err
+		}
+
+		for i := range filters {
+			if filters[i].Matches(event.Content) {
+				return nil
+			}
+		}
+	}
+
+	// Create the group, or move the existing unread group to the top with the actor as its latest.
+	notification := models.Notification{
+		RecipientID:   event.RecipientID,
+		Type:          event.Type,
+		GroupKey:      event.groupKey(),
+		PostID:        event.PostID,
+		LatestActorID: event.ActorID,
+		LatestAt:      time.Now(),
+	}
+	if err := n.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(
+			clause.OnConflict{
+				Columns:     []clause.Column{{Name: "recipient_id"}, {Name: "type"}, {Name: "group_key"}},
+				TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "read_at IS NULL"}}},
+				DoUpdates:   clause.AssignmentColumns([]string{"latest_actor_id", "latest_at", "updated_at"}),
+			},
+			// Return the ID of the existing group when there is a conflict.
+			clause.Returning{Columns: []clause.Column{{Name: "id"}}},
+		).Create(&notification).Error; err != nil {
+			return err
+		}
+
+		// Add the actor to the group, only counting them if they are new to it.
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.NotificationActor{
+			NotificationID: notification.ID,
+			ActorID:        event.ActorID,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&models.Notification{}).
+			Where("id = ?", notification.ID).
+			UpdateColumn("actor_count", gorm.Expr("actor_count + 1")).Error
+	}); err != nil {
+		return err
+	}
+
+	// Let the recipient's open streams know about the notification.
+	return n.broker.Publish(pubsub.UserTopic(event.RecipientID), pubsub.EventNotification, pubsub.NotificationEvent{
+		NotificationID: notification.ID,
+		Type:           notification.Type,
+		PostID:         notification.PostID,
+	})
+}
+
+// NotifyReply notifies the author of the post being replied to, it must only be called once the reply is published.
+func (n *Notifier) NotifyReply(reply models.Post) {
+	if reply.ParentID == nil {
+		return
+	}
+
+	// Get the author of the post being replied to.
+	var parent models.Post
+	if err := n.db.
+		Select("id", "author_id").
+		Where("id = ?", *reply.ParentID).
+		First(&parent).Error; err != nil {
+		slog.With("post", reply.ID, "error", err).Error("failed to find the parent of a reply")
+		return
+	}
+
+	n.Notify(Event{
+		Type:        models.NotificationReply,
+		RecipientID: parent.AuthorID,
+		ActorID:     reply.AuthorID,
+		PostID:      &parent.ID,
+		Content:     reply.Content,
+	})
+}
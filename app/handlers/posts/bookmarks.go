@@ -0,0 +1,94 @@
+package posts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+)
+
+// BookmarkPost handles the bookmarking of a single post by its ID.
+func (h *Handler) BookmarkPost(c *fiber.Ctx) error {
+	// Get the current session of the user that is bookmarking the post.
+	user := c.Locals("session").(models.Session)
+
+	// Get the post by its ID, only posts visible to the user can be bookmarked.
+	var post models.Post
+	if err := h.DB.Scopes(models.VisibleTo(user.Connection.UserID)).Where(models.Post{
+		BaseModel: models.BaseModel{ID: c.Params("post")},
+	}).First(&post).Error; err != nil {
+		return err
+	}
+
+	// Check if the user has already bookmarked the post.
+	var count int64
+	if err := h.DB.Model(&models.Bookmark{}).Where(models.Bookmark{
+		UserID: user.Connection.UserID,
+		PostID: post.ID,
+	}).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return utils.NewError(fiber.StatusConflict, "You have already bookmarked this post.", nil)
+	}
+
+	// Create the bookmark.
+	if err := h.DB.Create(&models.Bookmark{
+		UserID: user.Connection.UserID,
+		PostID: post.ID,
+	}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnbookmarkPost handles the removal of a bookmark on a single post by its ID.
+func (h *Handler) UnbookmarkPost(c *fiber.Ctx) error {
+	// Get the post by its ID.
+	var post models.Post
+	if err := h.DB.Where(models.Post{
+		BaseModel: models.BaseModel{ID: c.Params("post")},
+	}).First(&post).Error; err != nil {
+		return err
+	}
+
+	// Get the current session of the user that is removing the bookmark.
+	user := c.Locals("session").(models.Session)
+
+	// Delete the bookmark.
+	if err := h.DB.Where(models.Bookmark{
+		UserID: user.Connection.UserID,
+		PostID: post.ID,
+	}).Delete(&models.Bookmark{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ListBookmarks handles the retrieval of the current user's bookmarked posts, most recently bookmarked first.
+func (h *Handler) ListBookmarks(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Bookmarks are private, so they are only ever listed for the current user.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	posts, err := h.Repos.Posts.ListBookmarked(userID, page.Paginate)
+	if err != nil {
+		return err
+	}
+
+	// Apply the current user's mute filters to the extended posts.
+	extendedPosts, err := filterPosts(h.DB, extendPosts(posts, userID), userID, models.FilterScopeHome)
+	if err != nil {
+		return err
+	}
+
+	// Return the extended version of the posts.
+	return c.JSON(extendedPosts)
+}
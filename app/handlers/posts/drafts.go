@@ -0,0 +1,137 @@
+package posts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// scheduleMaxDuration is how far into the future a post can be scheduled.
+const scheduleMaxDuration = 365 * 24 * time.Hour
+
+// publishing returns the status of a new post and the time it will be published at.
+// Posts that are published straight away and drafts both use the current time.
+func publishing(body PostForm) (models.PostStatus, time.Time, error) {
+	now := time.Now()
+
+	switch {
+	case body.Draft && body.PublishAt != nil:
+		return "", now, utils.NewError(fiber.StatusBadRequest, "A post cannot be both a draft and scheduled.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "publish_at",
+					Errors: []string{"A post cannot be both a draft and scheduled."},
+				},
+			},
+		})
+	case body.Draft:
+		return models.PostStatusDraft, now, nil
+	case body.PublishAt != nil:
+		// Make sure the post is scheduled for a sensible time.
+		if !body.PublishAt.After(now) || body.PublishAt.Sub(now) > scheduleMaxDuration {
+			return "", now, utils.NewError(fiber.StatusBadRequest, "Posts must be scheduled for a time in the next year.", &utils.ErrorDetails{
+				Fields: []utils.ErrorField{
+					{
+						Name:   "publish_at",
+						Errors: []string{"Posts must be scheduled for a time in the next year."},
+					},
+				},
+			})
+		}
+		return models.PostStatusScheduled, *body.PublishAt, nil
+	default:
+		return models.PostStatusPublished, now, nil
+	}
+}
+
+// ListDrafts handles the retrieval of the current user's drafts, most recently updated first.
+func (h *Handler) ListDrafts(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Drafts are only ever listed for their author.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	posts, err := h.Repos.Posts.ListDrafts(userID, page.Paginate)
+	if err != nil {
+		return err
+	}
+
+	// Return the extended version of the posts.
+	return c.JSON(extendPosts(posts, userID))
+}
+
+// ListScheduled handles the retrieval of the current user's scheduled posts, the next to be published first.
+func (h *Handler) ListScheduled(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Scheduled posts are only ever listed for their author.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	posts, err := h.Repos.Posts.ListScheduled(userID, page.Paginate)
+	if err != nil {
+		return err
+	}
+
+	// Return the extended version of the posts.
+	return c.JSON(extendPosts(posts, userID))
+}
+
+// PublishPost handles publishing a draft or scheduled post straight away.
+func (h *Handler) PublishPost(c *fiber.Ctx) error {
+	// Get the current session for our author.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the post by its ID, only the author can publish their own posts.
+	var post models.Post
+	if err := h.DB.Where(models.Post{
+		BaseModel: models.BaseModel{ID: c.Params("post")},
+		AuthorID:  userID,
+	}).First(&post).Error; err != nil {
+		return err
+	}
+
+	// Publish the post and open its poll in a single transaction.
+	now := time.Now()
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Publish the post only if it has not been published yet, this guards against racing the job publishing scheduled posts.
+		// The creation time is moved to the time of publishing, so the post is ordered with other new posts.
+		result := tx.Model(&models.Post{}).
+			Where("id = ? AND status IN ?", post.ID, []models.PostStatus{models.PostStatusDraft, models.PostStatusScheduled}).
+			Updates(map[string]interface{}{
+				"status":     models.PostStatusPublished,
+				"publish_at": nil,
+				"created_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return utils.NewError(fiber.StatusConflict, "This post has already been published.", nil)
+		}
+
+		return models.OpenPoll(tx, post.ID, now)
+	}); err != nil {
+		return err
+	}
+
+	// Notify the author of the post being replied to, now that the reply is public.
+	h.notifier.NotifyReply(post)
+
+	// Push the post to the streams of the global feed and the author's followers, or the thread it replies to.
+	post.Status = models.PostStatusPublished
+	h.Events.PublishPost(post)
+	h.Events.PublishReply(post)
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,63 @@
+package posts
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+)
+
+// filterPosts applies the current user's mute filters for the scope to the extended posts.
+// Posts matched by a hide filter are dropped, and posts matched by a warn filter are marked with the filter that matched.
+func filterPosts(tx *gorm.DB, posts []ExtendedPost, userID string, scope models.FilterScope) ([]ExtendedPost, error) {
+	// Anonymous users have no filters.
+	if userID == "" || len(posts) == 0 {
+		return posts, nil
+	}
+
+	var filters []models.MuteFilter
+	if err := tx.
+		Scopes(models.ActiveFilters(userID, scope)).
+		Find(&filters).Error; err != nil {
+		return nil, err
+	}
+
+	if len(filters) == 0 {
+		return posts, nil
+	}
+
+	var filtered []ExtendedPost
+	for _, post := range posts {
+		// Users are never filtered from their own posts.
+		if post.AuthorID != userID {
+			post.Filtered = matchFilters(post.Content, filters)
+		}
+
+		// Drop the post if the match hides it.
+		if post.Filtered != nil && post.Filtered.Action == models.FilterActionHide {
+			continue
+		}
+
+		filtered = append(filtered, post)
+	}
+
+	return filtered, nil
+}
+
+// matchFilters returns the first filter matching the content, preferring filters that hide over filters that warn.
+func matchFilters(content string, filters []models.MuteFilter) *models.MuteFilter {
+	var match *models.MuteFilter
+	for i := range filters {
+		if !filters[i].Matches(content) {
+			continue
+		}
+
+		if filters[i].Action == models.FilterActionHide {
+			return &filters[i]
+		}
+
+		if match == nil {
+			match = &filters[i]
+		}
+	}
+
+	return match
+}
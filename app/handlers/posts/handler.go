@@ -0,0 +1,21 @@
+package posts
+
+import (
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/notifications"
+)
+
+// Handler serves the post endpoints.
+type Handler struct {
+	*app.App
+
+	notifier *notifications.Notifier
+}
+
+// New creates the post handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{
+		App:      a,
+		notifier: notifications.NewNotifier(a.DB, a.Broker),
+	}
+}
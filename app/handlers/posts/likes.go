@@ -2,53 +2,57 @@ package posts
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/handlers/notifications"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
 	"github.com/twibber/core/utils"
-	"gorm.io/gorm"
 )
 
 // LikePost handles the liking of a single post by its ID.
-func LikePost(c *fiber.Ctx) error {
-	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.
-		Preload("Likes").
-		Where(models.Post{
-			BaseModel: models.BaseModel{ID: c.Params("post")},
-		}).First(&post).Error; err != nil {
-		return err
-	}
-
+func (h *Handler) LikePost(c *fiber.Ctx) error {
 	// Get the current session of the user that is liking the post.
 	user := c.Locals("session").(models.Session)
 
+	// Get the post by its ID, only posts visible to the user can be liked.
+	post, err := h.Repos.Posts.FindVisible(c.Params("post"), user.Connection.UserID)
+	if err != nil {
+		return err
+	}
+
 	// Check if the user has already liked the post.
-	for _, like := range post.Likes {
-		if like.LikedByID == c.Locals("session").(models.Session).Connection.UserID {
-			return utils.NewError(fiber.StatusConflict, "You have already liked this post.", nil)
-		}
+	liked, err := h.Repos.Likes.Exists(post.ID, user.Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	if liked {
+		return utils.NewError(fiber.StatusConflict, "You have already liked this post.", nil)
 	}
 
 	// Create the like.
-	if err := db.DB.Create(&models.Like{
-		LikedByID: user.Connection.UserID,
-		PostID:    post.ID,
-	}).Error; err != nil {
+	if err := h.Repos.Likes.Create(post.ID, user.Connection.UserID); err != nil {
 		return err
 	}
 
+	// Notify the author of the post.
+	h.notifier.Notify(notifications.Event{
+		Type:        models.NotificationLike,
+		RecipientID: post.AuthorID,
+		ActorID:     user.Connection.UserID,
+		PostID:      &post.ID,
+	})
+
+	// Push the new like count to the subscribers of the thread.
+	h.Events.PublishLikes(post)
+
 	// Return the created like.
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // UnlikePost handles the unliking of a single post by its ID.
-func UnlikePost(c *fiber.Ctx) error {
+func (h *Handler) UnlikePost(c *fiber.Ctx) error {
 	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.Where(models.Post{
-		BaseModel: models.BaseModel{ID: c.Params("post")},
-	}).First(&post).Error; err != nil {
+	post, err := h.Repos.Posts.Find(c.Params("post"))
+	if err != nil {
 		return err
 	}
 
@@ -56,36 +60,31 @@ func UnlikePost(c *fiber.Ctx) error {
 	user := c.Locals("session").(models.Session)
 
 	// Delete the like.
-	if err := db.DB.Where(models.Like{
-		LikedByID: user.Connection.UserID,
-		PostID:    post.ID,
-	}).Delete(&models.Like{}).Error; err != nil {
+	if err := h.Repos.Likes.Delete(post.ID, user.Connection.UserID); err != nil {
 		return err
 	}
 
+	// Push the new like count to the subscribers of the thread.
+	h.Events.PublishLikes(post)
+
 	// Return the deleted like.
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // ListPostLikes handles the retrieval of all likes on a single post by its ID.
-func ListPostLikes(c *fiber.Ctx) error {
-	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.Where(models.Post{
-		BaseModel: models.BaseModel{ID: c.Params("post")},
-	}).First(&post).Error; err != nil {
+func (h *Handler) ListPostLikes(c *fiber.Ctx) error {
+	// Get the current session of the user listing the likes.
+	user := c.Locals("session").(models.Session)
+
+	// Get the post by its ID, the likes are hidden along with the post.
+	post, err := h.Repos.Posts.FindVisible(c.Params("post"), user.Connection.UserID)
+	if err != nil {
 		return err
 	}
 
-	// Get all likes on the post.
-	var likes []models.Like
-	if err := db.DB.
-		// Get the user that liked the post
-		Preload("LikedBy", func(db *gorm.DB) *gorm.DB {
-			return db.Omit("Email") // Omit the email of the author for privacy reasons.
-		}).
-		Where(models.Like{PostID: post.ID}).
-		Find(&likes).Error; err != nil {
+	// Get all likes on the post, along with the users that liked it.
+	likes, err := h.Repos.Likes.ListVisible(post.ID, user.Connection.UserID)
+	if err != nil {
 		return err
 	}
 
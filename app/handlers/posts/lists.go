@@ -0,0 +1,72 @@
+package posts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/utils"
+)
+
+// ListTimeline handles the retrieval of the posts made by the accounts in a list, most recent first.
+// The list owner's blocks, mutes and filters are applied, as well as the current user's own.
+func (h *Handler) ListTimeline(c *fiber.Ctx) error {
+	// Get the requested page.
+	page, err := utils.ParsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	// Get the id of the current user.
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Get the list, the timeline is hidden along with it.
+	var list models.List
+	if err := h.DB.
+		Scopes(models.ListVisibleTo(userID)).
+		Where(models.List{BaseModel: models.BaseModel{ID: c.Params("list")}}).
+		First(&list).Error; err != nil {
+		return err
+	}
+
+	var posts []models.Post
+	if err := h.DB.
+		Scopes(
+			models.VisibleTo(userID),
+			models.NotMutedBy(userID),
+			models.NotMutedBy(list.OwnerID),
+			repository.PreloadExtended("", userID),
+		).
+		Joins("JOIN list_members ON list_members.user_id = posts.author_id AND list_members.list_id = ?", list.ID).
+		// Hide the posts of anyone the owner has blocked or been blocked by since they were added.
+		Where("NOT EXISTS (SELECT 1 FROM blocks WHERE (blocks.blocker_id = ? AND blocks.blocked_id = posts.author_id) OR (blocks.blocker_id = posts.author_id AND blocks.blocked_id = ?))",
+			list.OwnerID, list.OwnerID).
+		// only get top level posts
+		Where("posts.parent_id IS NULL").
+		Order("posts.created_at desc").
+		Scopes(page.Paginate).
+		Find(&posts).Error; err != nil {
+		return err
+	}
+
+	// Apply the owner's mute filters to the extended posts.
+	extendedPosts, err := filterPosts(h.DB, extendPosts(posts, userID), list.OwnerID, models.FilterScopeHome)
+	if err != nil {
+		return err
+	}
+
+	if userID != list.OwnerID {
+		// The owner's filters are private, so only the posts they hide are dropped for everyone else.
+		for i := range extendedPosts {
+			extendedPosts[i].Filtered = nil
+		}
+
+		// Apply the current user's mute filters as well.
+		extendedPosts, err = filterPosts(h.DB, extendedPosts, userID, models.FilterScopeHome)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Return the extended version of the posts.
+	return c.JSON(extendedPosts)
+}
@@ -0,0 +1,46 @@
+package posts
+
+import (
+	"github.com/twibber/core/app/models"
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @username mentions, following the same rules as usernames at registration.
+var mentionPattern = regexp.MustCompile(`@([a-zA-Z]{3,64})\b`)
+
+// findMentions resolves the users mentioned in the content of a post by the author.
+// Unknown usernames are ignored, as are users with a block in either direction with the author.
+func (h *Handler) findMentions(content, authorID string) ([]models.Mention, error) {
+	// Collect the unique usernames mentioned, usernames are always lowercase.
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.ToLower(match[1])
+		if !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	// Get the users that exist from the usernames.
+	var users []models.User
+	if err := h.DB.
+		Select("id").
+		Where("username IN ?", usernames).
+		Where("NOT EXISTS (SELECT 1 FROM blocks WHERE (blocks.blocker_id = ? AND blocks.blocked_id = users.id) OR (blocks.blocker_id = users.id AND blocks.blocked_id = ?))", authorID, authorID).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	var mentions []models.Mention
+	for _, user := range users {
+		mentions = append(mentions, models.Mention{UserID: user.ID})
+	}
+
+	return mentions, nil
+}
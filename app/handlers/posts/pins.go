@@ -0,0 +1,80 @@
+package posts
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+)
+
+// PinPost handles pinning one of the current user's top-level posts to their profile.
+func (h *Handler) PinPost(c *fiber.Ctx) error {
+	// Get the current session of the author.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the post by its ID, only published top-level posts can be pinned by their author.
+	var post models.Post
+	if err := h.DB.Where(models.Post{
+		BaseModel: models.BaseModel{ID: c.Params("post")},
+		AuthorID:  userID,
+		Status:    models.PostStatusPublished,
+	}).First(&post).Error; err != nil {
+		return err
+	}
+
+	if post.ParentID != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Replies cannot be pinned.", nil)
+	}
+
+	// Check the author has not reached the limit of pinned posts.
+	var count int64
+	if err := h.DB.Model(&models.Pin{}).
+		Where(models.Pin{UserID: userID}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count >= int64(h.Config.MaxPinnedPosts) {
+		return utils.NewError(fiber.StatusForbidden, "You have reached the maximum number of pinned posts.", nil)
+	}
+
+	if err := h.DB.Create(&models.Pin{
+		UserID: userID,
+		PostID: post.ID,
+	}).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.NewError(fiber.StatusConflict, "You have already pinned this post.", nil)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnpinPost handles unpinning one of the current user's posts from their profile.
+func (h *Handler) UnpinPost(c *fiber.Ctx) error {
+	// Get the current session of the author.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	if err := h.DB.Where(models.Pin{
+		UserID: userID,
+		PostID: c.Params("post"),
+	}).Delete(&models.Pin{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ListPinned returns the posts pinned by the user which are visible to the viewer, in the order they were pinned.
+// They are extended and filtered for the viewer the same way as the pinned posts returned first by GetUserPosts.
+func ListPinned(tx *gorm.DB, userID, viewerID string) ([]ExtendedPost, error) {
+	posts, err := repository.New(tx).Posts.ListPinnedExtended(userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterPosts(tx, extendPosts(posts, viewerID), viewerID, models.FilterScopeHome)
+}
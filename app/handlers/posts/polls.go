@@ -0,0 +1,220 @@
+package posts
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Bounds on how long a poll can accept votes for.
+const (
+	pollMinDuration = 5 * time.Minute
+	pollMaxDuration = 7 * 24 * time.Hour
+)
+
+// PollForm is used to parse a poll attached to a new post.
+type PollForm struct {
+	Options   []string  `json:"options" validate:"required,min=2,max=4,unique,dive,required,max=64"`
+	Multiple  bool      `json:"multiple"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+// newPoll creates the poll model for a new post, it returns nil when no poll was provided.
+// The poll opens when the post is published, so its duration is measured from opensAt,
+// and drafts and scheduled posts published at another time keep the duration, see models.OpenPoll.
+func newPoll(form *PollForm, opensAt time.Time) (*models.Poll, error) {
+	if form == nil {
+		return nil, nil
+	}
+
+	// Make sure the poll is open for a sensible amount of time.
+	duration := form.ExpiresAt.Sub(opensAt)
+	if duration < pollMinDuration || duration > pollMaxDuration {
+		return nil, utils.NewError(fiber.StatusBadRequest, "The poll must close between 5 minutes and 7 days from now.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "poll",
+					Errors: []string{"The poll must close between 5 minutes and 7 days from now."},
+				},
+			},
+		})
+	}
+
+	poll := models.Poll{
+		Multiple:  form.Multiple,
+		ExpiresAt: form.ExpiresAt,
+		Duration:  duration,
+	}
+
+	// Keep the options in the order they were provided.
+	for i, text := range form.Options {
+		poll.Options = append(poll.Options, models.PollOption{
+			Position: i,
+			Text:     text,
+		})
+	}
+
+	return &poll, nil
+}
+
+// ExtendedPoll represents a poll with its results, the results are only included once they can be revealed to the current user.
+type ExtendedPoll struct {
+	models.Poll
+
+	Closed  bool                 `json:"closed"`            // Whether the poll has stopped accepting votes.
+	Voted   bool                 `json:"voted"`             // Whether the current user voted in the poll.
+	Choices []string             `json:"choices,omitempty"` // The options chosen by the current user.
+	Options []ExtendedPollOption `json:"options"`           // The options of the poll, with their results if revealed.
+	Total   *int64               `json:"total,omitempty"`   // Total ballots cast, only included if the results are revealed.
+}
+
+// ExtendedPollOption represents a poll option with its vote count.
+type ExtendedPollOption struct {
+	models.PollOption
+
+	Votes *int64 `json:"votes,omitempty"` // Votes for the option, only included if the results are revealed.
+}
+
+// extendPoll extends a poll with its results, hiding them until the current user has voted or the poll has closed.
+func extendPoll(poll models.Poll, userID string) *ExtendedPoll {
+	extendedPoll := ExtendedPoll{
+		Poll:   poll,
+		Closed: poll.Closed(),
+	}
+
+	// Count the votes for every option and find the current user's ballot.
+	counts := make(map[string]int64)
+	for _, vote := range poll.Votes {
+		for _, choice := range vote.Choices {
+			counts[choice.OptionID]++
+		}
+
+		if userID != "" && vote.UserID == userID {
+			extendedPoll.Voted = true
+			for _, choice := range vote.Choices {
+				extendedPoll.Choices = append(extendedPoll.Choices, choice.OptionID)
+			}
+		}
+	}
+
+	// Only reveal the results to users who have voted, or once the poll is closed.
+	revealed := extendedPoll.Voted || extendedPoll.Closed
+	if revealed {
+		total := int64(len(poll.Votes))
+		extendedPoll.Total = &total
+	}
+
+	for _, option := range poll.Options {
+		extendedOption := ExtendedPollOption{PollOption: option}
+		if revealed {
+			count := counts[option.ID]
+			extendedOption.Votes = &count
+		}
+
+		extendedPoll.Options = append(extendedPoll.Options, extendedOption)
+	}
+
+	return &extendedPoll
+}
+
+// VoteForm is used to parse the request body for votes in a poll.
+type VoteForm struct {
+	Options []string `json:"options" validate:"required,min=1,max=4,unique,dive,required"`
+}
+
+// VotePoll handles voting in the poll attached to a single post by its ID.
+func (h *Handler) VotePoll(c *fiber.Ctx) error {
+	// Get the request body and validate it.
+	var body VoteForm
+	if err := utils.ParseAndValidate(c, &body); err != nil {
+		return err
+	}
+
+	// Get the current session of the user that is voting.
+	userID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the post by its ID along with its poll.
+	var post models.Post
+	if err := h.DB.
+		Scopes(models.VisibleTo(userID)).
+		Preload("Poll.Options").
+		Where(models.Post{
+			BaseModel: models.BaseModel{ID: c.Params("post")},
+		}).
+		First(&post).Error; err != nil {
+		return err
+	}
+
+	if post.Poll == nil {
+		return utils.NewError(fiber.StatusNotFound, "This post does not have a poll.", nil)
+	}
+
+	poll := post.Poll
+
+	// Check if the poll is still accepting votes.
+	if poll.Closed() {
+		return utils.NewError(fiber.StatusForbidden, "This poll has closed.", nil)
+	}
+
+	// Only multiple choice polls accept more than one option.
+	if !poll.Multiple && len(body.Options) > 1 {
+		return utils.NewError(fiber.StatusBadRequest, "Only one option can be chosen in this poll.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "options",
+					Errors: []string{"Only one option can be chosen in this poll."},
+				},
+			},
+		})
+	}
+
+	// Make sure every option chosen belongs to this poll.
+	valid := make(map[string]bool)
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+
+	vote := models.PollVote{
+		PollID: poll.ID,
+		UserID: userID,
+	}
+	for _, optionID := range body.Options {
+		if !valid[optionID] {
+			return utils.NewError(fiber.StatusBadRequest, "One or more of the options chosen are not part of this poll.", &utils.ErrorDetails{
+				Fields: []utils.ErrorField{
+					{
+						Name:   "options",
+						Errors: []string{"One or more of the options chosen are not part of this poll."},
+					},
+				},
+			})
+		}
+
+		vote.Choices = append(vote.Choices, models.PollChoice{OptionID: optionID})
+	}
+
+	// Create the ballot and its choices together, the unique index on the ballot stops double voting.
+	if err := h.DB.Create(&vote).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return utils.NewError(fiber.StatusConflict, "You have already voted in this poll.", nil)
+		}
+		return err
+	}
+
+	// Return the post with the now revealed results.
+	var result models.Post
+	if err := h.DB.
+		Scopes(repository.PreloadExtended("", userID)).
+		Where(models.Post{
+			BaseModel: models.BaseModel{ID: post.ID},
+		}).
+		First(&result).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(extendPost(result, userID))
+}
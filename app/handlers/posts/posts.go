@@ -1,73 +1,181 @@
 package posts
 
 import (
+	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/handlers/notifications"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
 	"github.com/twibber/core/utils"
-	"gorm.io/gorm"
 	"time"
+	"unicode/utf8"
 )
 
 type PostForm struct {
-	Content string `json:"content" validate:"required,max=512"`
+	Content string    `json:"content" validate:"required"` // Limited to the configured length by checkContent.
+	Poll    *PollForm `json:"poll" validate:"omitempty"`   // Optional poll attached to the post.
+
+	// Who can see the post, public if not provided.
+	Visibility models.Visibility `json:"visibility" validate:"omitempty,oneof=public followers mentioned"`
+
+	// Optionally hold the post back from being published.
+	Draft     bool       `json:"draft"`      // Save the post as a draft instead of publishing it.
+	PublishAt *time.Time `json:"publish_at"` // Schedule the post to be published at a later time.
+}
+
+// checkContent makes sure the content of a post is within the configured length.
+func (h *Handler) checkContent(content string) error {
+	if utf8.RuneCountInString(content) > h.Config.PostMaxLength {
+		message := fmt.Sprintf("Posts can be at most %d characters long.", h.Config.PostMaxLength)
+		return utils.NewError(fiber.StatusBadRequest, message, &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "content",
+					Errors: []string{message},
+				},
+			},
+		})
+	}
+
+	return nil
 }
 
 // CreatePost handles the creation of new posts.
-func CreatePost(c *fiber.Ctx) error {
+func (h *Handler) CreatePost(c *fiber.Ctx) error {
 	// Get the request body and validate it.
 	var body PostForm
 	if err := utils.ParseAndValidate(c, &body); err != nil {
 		return err
 	}
 
+	if err := h.checkContent(body.Content); err != nil {
+		return err
+	}
+
 	// Get the current session for our author.
 	user := c.Locals("session").(models.Session)
 
+	// Work out if the post is being published now, saved as a draft or scheduled.
+	status, publishAt, err := publishing(body)
+	if err != nil {
+		return err
+	}
+
+	// Create the poll if one was provided, it opens when the post is published.
+	poll, err := newPoll(body.Poll, publishAt)
+	if err != nil {
+		return err
+	}
+
+	// Find the users mentioned in the post.
+	mentions, err := h.findMentions(body.Content, user.Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Default to a public post.
+	if body.Visibility == "" {
+		body.Visibility = models.VisibilityPublic
+	}
+
 	// Create the post.
 	post := models.Post{
-		AuthorID: user.Connection.UserID,
-		Content:  body.Content,
+		AuthorID:   user.Connection.UserID,
+		Content:    body.Content,
+		Poll:       poll,
+		Status:     status,
+		Visibility: body.Visibility,
+		Mentions:   mentions,
+	}
+	if status == models.PostStatusScheduled {
+		post.PublishAt = &publishAt
 	}
-	if err := db.DB.Create(&post).Error; err != nil {
+
+	if err := h.Repos.Posts.Create(&post); err != nil {
 		return err
 	}
 
+	// Push the post to the streams of the global feed and the author's followers.
+	h.Events.PublishPost(post)
+
 	// Return the created post.
 	return c.JSON(post)
 }
 
-func CreateReply(c *fiber.Ctx) error {
+func (h *Handler) CreateReply(c *fiber.Ctx) error {
 	// Get the request body and validate it.
 	var body PostForm
 	if err := utils.ParseAndValidate(c, &body); err != nil {
 		return err
 	}
 
-	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.Where(models.Post{
-		BaseModel: models.BaseModel{ID: c.Params("post")},
-	}).First(&post).Error; err != nil {
+	if err := h.checkContent(body.Content); err != nil {
 		return err
 	}
 
 	// Get the current session for our author.
 	user := c.Locals("session").(models.Session)
 
+	// Get the post by its ID, only posts visible to the author can be replied to.
+	post, err := h.Repos.Posts.FindVisible(c.Params("post"), user.Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Work out if the post is being published now, saved as a draft or scheduled.
+	status, publishAt, err := publishing(body)
+	if err != nil {
+		return err
+	}
+
+	// Create the poll if one was provided, it opens when the post is published.
+	poll, err := newPoll(body.Poll, publishAt)
+	if err != nil {
+		return err
+	}
+
+	// Find the users mentioned in the post.
+	mentions, err := h.findMentions(body.Content, user.Connection.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Replies are at least as restricted as the post they reply to.
+	visibility := models.VisibilityPublic.Restrict(body.Visibility).Restrict(post.Visibility)
+
 	// Create the reply.
 	reply := models.Post{
-		AuthorID: user.Connection.UserID,
-		Content:  body.Content,
+		AuthorID:   user.Connection.UserID,
+		Content:    body.Content,
+		Poll:       poll,
+		Status:     status,
+		Visibility: visibility,
+		Mentions:   mentions,
 		// Set the parent ID to the ID of the post we are replying to.
 		ParentID: &post.ID,
 	}
+	if status == models.PostStatusScheduled {
+		reply.PublishAt = &publishAt
+	}
 
 	// Create the reply in the database and return any errors.
-	if err := db.DB.Create(&reply).Error; err != nil {
+	if err := h.Repos.Posts.Create(&reply); err != nil {
 		return err
 	}
 
+	// Notify the author of the post being replied to, drafts and scheduled replies notify once published.
+	if reply.Status == models.PostStatusPublished {
+		h.notifier.Notify(notifications.Event{
+			Type:        models.NotificationReply,
+			RecipientID: post.AuthorID,
+			ActorID:     reply.AuthorID,
+			PostID:      &post.ID,
+			Content:     reply.Content,
+		})
+	}
+
+	// Push the reply to the subscribers of the thread, this is a no-op until it is published.
+	h.Events.PublishReply(reply)
+
 	// Return the created post.
 	return c.JSON(reply)
 }
@@ -76,8 +184,13 @@ func CreateReply(c *fiber.Ctx) error {
 type ExtendedPost struct {
 	models.Post
 
-	Liked  bool       `json:"liked"`  // Whether the current user liked the post.
-	Counts PostCounts `json:"counts"` // The counts of the post.
+	Liked      bool       `json:"liked"`      // Whether the current user liked the post.
+	Bookmarked bool       `json:"bookmarked"` // Whether the current user bookmarked the post.
+	Counts     PostCounts `json:"counts"`     // The counts of the post.
+
+	Poll *ExtendedPoll `json:"poll,omitempty"` // The poll attached to the post, if any.
+
+	Filtered *models.MuteFilter `json:"filtered,omitempty"` // The current user's mute filter that matched the post, if any.
 }
 
 type PostCounts struct {
@@ -89,14 +202,20 @@ type PostCounts struct {
 func extendPost(post models.Post, userID string) ExtendedPost {
 	// define the extended post
 	extendedPost := ExtendedPost{
-		Post:  post,
-		Liked: false,
+		Post:       post,
+		Liked:      false,
+		Bookmarked: false,
 		Counts: PostCounts{
 			Likes:   int64(len(post.Likes)),
 			Replies: int64(len(post.Replies)),
 		},
 	}
 
+	// Extend the poll, if there is one, hiding the results where needed.
+	if post.Poll != nil {
+		extendedPost.Poll = extendPoll(*post.Poll, userID)
+	}
+
 	// Check if the current user is logged in
 	if userID != "" {
 		// Check if the current user liked the post.
@@ -106,35 +225,47 @@ func extendPost(post models.Post, userID string) ExtendedPost {
 				break
 			}
 		}
+
+		// Check if the current user bookmarked the post.
+		for _, bookmark := range post.Bookmarks {
+			if bookmark.UserID == userID {
+				extendedPost.Bookmarked = true
+				break
+			}
+		}
 	}
 
 	return extendedPost
 }
 
+// extendPosts extends every post in the slice, preserving the order of the posts.
+func extendPosts(posts []models.Post, userID string) []ExtendedPost {
+	var extendedPosts []ExtendedPost
+	for _, post := range posts {
+		extendedPosts = append(extendedPosts, extendPost(post, userID))
+	}
+
+	return extendedPosts
+}
+
 // ListPosts handles the retrieval of all posts with their like counts and whether the current user liked the post.
-func ListPosts(c *fiber.Ctx) error {
+func (h *Handler) ListPosts(c *fiber.Ctx) error {
+	// Get the id of the current user
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Read from a replica, unless the current user has just written.
+	reader := h.Read(c)
+
 	// Get all posts.
-	var posts []models.Post
-	if err := db.DB.
-		Preload("Likes").
-		Preload("Replies").
-		Preload("Author", func(db *gorm.DB) *gorm.DB {
-			return db.Omit("Email") // Omit the email of the author for privacy reasons.
-		}).
-		// only get top level posts
-		Where("parent_id IS NULL").
-		Order("created_at desc").
-		Find(&posts).Error; err != nil {
+	posts, err := h.ReadRepos(c).Posts.ListTimeline(userID)
+	if err != nil {
 		return err
 	}
 
-	// Get the id of the current user
-	userID := utils.GetUserID(c)
-
-	// Loop through the posts and extend them.
-	var extendedPosts []ExtendedPost
-	for _, post := range posts {
-		extendedPosts = append(extendedPosts, extendPost(post, userID))
+	// Apply the current user's mute filters to the extended posts.
+	extendedPosts, err := filterPosts(reader, extendPosts(posts, userID), userID, models.FilterScopeHome)
+	if err != nil {
+		return err
 	}
 
 	// Return the extended version of the posts.
@@ -142,59 +273,58 @@ func ListPosts(c *fiber.Ctx) error {
 }
 
 // GetPost handles the retrieval of a single post by its ID.
-func GetPost(c *fiber.Ctx) error {
+func (h *Handler) GetPost(c *fiber.Ctx) error {
+	// Get the id of the current user
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
 	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.
-		Preload("Likes").
-		Preload("Author", func(db *gorm.DB) *gorm.DB {
-			return db.Omit("Email") // Omit the email of the author for privacy reasons.
-		}).
-		Where(models.Post{
-			BaseModel: models.BaseModel{ID: c.Params("post")},
-		}).
-		First(&post).Error; err != nil {
+	post, err := h.Repos.Posts.FindExtended(c.Params("post"), userID)
+	if err != nil {
 		return err
 	}
 
-	// Get the id of the current user
-	userID := utils.GetUserID(c)
-
 	return c.JSON(extendPost(post, userID))
 }
 
 // GetUserPosts returns the posts made by the specified user
-func GetUserPosts(c *fiber.Ctx) error {
+func (h *Handler) GetUserPosts(c *fiber.Ctx) error {
+	// Get the id of the current user
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Read from a replica, unless the current user has just written.
+	reader := h.Read(c)
+	repos := h.ReadRepos(c)
+
 	// get user by username
-	var user models.User
-	if err := db.DB.
-		Where(models.User{Username: c.Params("user")}).
-		First(&user).Error; err != nil {
+	user, err := repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
 		return err
 	}
 
+	// Optionally return the pinned posts first, in the order they were pinned.
 	var posts []models.Post
-	if err := db.DB.
-		Preload("Likes").
-		Preload("Replies").
-		Preload("Author", func(db *gorm.DB) *gorm.DB {
-			return db.Omit("Email") // Omit the email of the author for privacy reasons.
-		}).
-		Where(models.Post{
-			AuthorID: user.ID,
-		}).
-		Order("created_at desc").
-		Find(&posts).Error; err != nil {
-		return err
+	if c.QueryBool("pinned_first") {
+		if posts, err = repos.Posts.ListPinnedExtended(user.ID, userID); err != nil {
+			return err
+		}
 	}
 
-	// Get the id of the current user
-	userID := utils.GetUserID(c)
-
-	// Loop through the posts and extend them.
-	var extendedPosts []ExtendedPost
+	// Skip the pinned posts already returned.
+	var pinned []string
 	for _, post := range posts {
-		extendedPosts = append(extendedPosts, extendPost(post, userID))
+		pinned = append(pinned, post.ID)
+	}
+
+	rest, err := repos.Posts.ListByAuthor(user.ID, userID, pinned)
+	if err != nil {
+		return err
+	}
+	posts = append(posts, rest...)
+
+	// Apply the current user's mute filters to the extended posts.
+	extendedPosts, err := filterPosts(reader, extendPosts(posts, userID), userID, models.FilterScopeHome)
+	if err != nil {
+		return err
 	}
 
 	// Return the extended version of the posts.
@@ -202,41 +332,35 @@ func GetUserPosts(c *fiber.Ctx) error {
 }
 
 // ListPostReplies handles the retrieval of all replies to a single post by its ID.
-func ListPostReplies(c *fiber.Ctx) error {
+func (h *Handler) ListPostReplies(c *fiber.Ctx) error {
+	// Get the id of the current user
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Read from a replica, unless the current user has just written.
+	reader := h.Read(c)
+
 	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.
-		Preload("Replies.Likes").
-		Preload("Replies.Author", func(db *gorm.DB) *gorm.DB {
-			return db.Omit("Email") // Omit the email of the author for privacy reasons.
-		}).
-		Where(models.Post{
-			BaseModel: models.BaseModel{ID: c.Params("post")},
-		}).
-		First(&post).Error; err != nil {
+	post, err := h.ReadRepos(c).Posts.FindWithReplies(c.Params("post"), userID)
+	if err != nil {
 		return err
 	}
 
-	// Get the id of the current user
-	userID := utils.GetUserID(c)
-
-	// Loop through the replies and extend them.
-	var extendedReplies []ExtendedPost
-	for _, reply := range post.Replies {
-		extendedReplies = append(extendedReplies, extendPost(reply, userID))
+	// Apply the current user's mute filters to the extended replies.
+	extendedReplies, err := filterPosts(reader, extendPosts(post.Replies, userID), userID, models.FilterScopeReplies)
+	if err != nil {
+		return err
 	}
 
 	// Return the extended version of the replies.
 	return c.JSON(extendedReplies)
 }
 
-// DeletePost handles the deletion of a single post by its ID as long as the author is the one making the request, and it was created within the last 5 minutes.
-func DeletePost(c *fiber.Ctx) error {
+// DeletePost handles the deletion of a single post by its ID as long as the author is the one making the request, and it was published within the configured window.
+// Drafts and scheduled posts have never been public, so they can be deleted at any time.
+func (h *Handler) DeletePost(c *fiber.Ctx) error {
 	// Get the post by its ID.
-	var post models.Post
-	if err := db.DB.Where(models.Post{
-		BaseModel: models.BaseModel{ID: c.Params("post")},
-	}).First(&post).Error; err != nil {
+	post, err := h.Repos.Posts.Find(c.Params("post"))
+	if err != nil {
 		return err
 	}
 
@@ -248,13 +372,14 @@ func DeletePost(c *fiber.Ctx) error {
 		return utils.NewError(fiber.StatusForbidden, "You are not the author of this post.", nil)
 	}
 
-	// Check if the post was created within the last 5 minutes.
-	if time.Since(post.CreatedAt) > 5*time.Minute {
-		return utils.NewError(fiber.StatusForbidden, "You can only delete posts created within the last 5 minutes.", nil)
+	// Check if the post was published within the delete window.
+	window := h.Config.PostDeleteWindow
+	if post.Status == models.PostStatusPublished && time.Since(post.CreatedAt) > window {
+		return utils.NewError(fiber.StatusForbidden, fmt.Sprintf("You can only delete posts published within the last %s.", window), nil)
 	}
 
 	// Delete the post.
-	if err := db.DB.Delete(&post).Error; err != nil {
+	if err := h.Repos.Posts.Delete(&post); err != nil {
 		return err
 	}
 
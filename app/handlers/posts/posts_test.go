@@ -0,0 +1,343 @@
+package posts_test
+
+import (
+	"github.com/twibber/core/app/apptest"
+	"github.com/twibber/core/app/handlers/posts"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreatePost(t *testing.T) {
+	h := apptest.New(t)
+	user, cookie := h.SignUp("alice")
+
+	var post models.Post
+	h.DecodeJSON(h.Post("/posts", map[string]string{"content": "hello world"}, cookie), http.StatusOK, &post)
+
+	if post.ID == "" || post.AuthorID != user.ID || post.Content != "hello world" {
+		t.Fatalf("expected the created post, got %+v", post)
+	}
+	if post.Status != models.PostStatusPublished || post.Visibility != models.VisibilityPublic {
+		t.Errorf("expected a published public post, got %s %s", post.Status, post.Visibility)
+	}
+}
+
+func TestCreatePostErrors(t *testing.T) {
+	h := apptest.New(t, func(config *cfg.Configuration) {
+		config.PostMaxLength = 10
+	})
+	_, verified := h.SignUp("alice")
+	unverified := h.CreateSession(h.CreateConnection(h.CreateUser("bob"), false))
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		cookie *http.Cookie
+		status int
+		code   string
+		field  string
+	}{
+		{"no session", map[string]string{"content": "hello"}, nil, http.StatusUnauthorized, "UNAUTHORIZED", ""},
+		{"unverified", map[string]string{"content": "hello"}, unverified, http.StatusForbidden, "UNVERIFIED", ""},
+		{"no content", map[string]string{}, verified, http.StatusBadRequest, "BAD_REQUEST", "content"},
+		{"too long", map[string]string{"content": strings.Repeat("a", 11)}, verified, http.StatusBadRequest, "BAD_REQUEST", "content"},
+		{"unknown visibility", map[string]string{"content": "hello", "visibility": "secret"}, verified, http.StatusBadRequest, "BAD_REQUEST", "visibility"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cookies []*http.Cookie
+			if test.cookie != nil {
+				cookies = append(cookies, test.cookie)
+			}
+
+			e := h.ExpectError(h.Post("/posts", test.body, cookies...), test.status, test.code)
+			if test.field != "" && !apptest.HasFieldError(e, test.field) {
+				t.Errorf("expected an error for %s, got %+v", test.field, e.Details)
+			}
+		})
+	}
+}
+
+func TestGetPost(t *testing.T) {
+	h := apptest.New(t)
+	alice, cookie := h.SignUp("alice")
+	bob := h.CreateUser("bob")
+
+	post := h.CreatePost(alice, "hello world")
+	h.CreateReply(bob, post, "hi alice")
+	h.CreateLike(alice, post)
+	h.CreateLike(bob, post)
+
+	var extended posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts/"+post.ID, cookie), http.StatusOK, &extended)
+
+	if extended.ID != post.ID || extended.Content != "hello world" {
+		t.Fatalf("expected the post, got %+v", extended.Post)
+	}
+	if extended.Counts.Likes != 2 || extended.Counts.Replies != 1 {
+		t.Errorf("expected 2 likes and 1 reply, got %+v", extended.Counts)
+	}
+	if !extended.Liked {
+		t.Error("expected the post to be liked by the current user")
+	}
+
+	// Without a session nothing is liked.
+	h.DecodeJSON(h.Get("/posts/"+post.ID), http.StatusOK, &extended)
+	if extended.Liked {
+		t.Error("expected the post not to be liked without a session")
+	}
+}
+
+func TestGetPostHidesProtectedLikes(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	carol := h.CreateUser("carol", func(user *models.User) {
+		user.Protected = true
+	})
+	_, bob := h.SignUp("bob")
+
+	post := h.CreatePost(alice, "hello world")
+	h.CreateLike(alice, post)
+	h.CreateLike(carol, post)
+
+	// Bob does not follow carol, so her like is neither counted nor listed, whether alone or in a listing.
+	var extended posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts/"+post.ID, bob), http.StatusOK, &extended)
+	if extended.Counts.Likes != 1 || len(extended.Likes) != 1 || extended.Likes[0].LikedByID != alice.ID {
+		t.Errorf("expected only the like by alice, got %d likes %+v", extended.Counts.Likes, extended.Likes)
+	}
+
+	var listed []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts", bob), http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].Counts.Likes != 1 || len(listed[0].Likes) != 1 {
+		t.Errorf("expected the listed post to only have the like by alice, got %+v", listed)
+	}
+}
+
+func TestGetPostHidesBlockedLikes(t *testing.T) {
+	h := apptest.New(t)
+	alice, cookie := h.SignUp("alice")
+	bob, bobCookie := h.SignUp("bob")
+
+	carol := h.CreateUser("carol")
+
+	post := h.CreatePost(alice, "hello world")
+	other := h.CreatePost(carol, "hello everyone")
+	h.CreateLike(bob, post)
+	h.CreateLike(bob, other)
+
+	if err := h.App.DB.Create(&models.Block{BlockerID: alice.ID, BlockedID: bob.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Likes across a block are hidden from the blocker, on their own posts and everyone else's.
+	var listed []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts", cookie), http.StatusOK, &listed)
+	if len(listed) != 2 {
+		t.Fatalf("expected both posts, got %d", len(listed))
+	}
+	for _, p := range listed {
+		if p.Counts.Likes != 0 || len(p.Likes) != 0 {
+			t.Errorf("expected the like by bob to be hidden from alice, got %+v", p)
+		}
+	}
+
+	// The blocked user no longer sees their like on the blocker's post either, as the post is hidden from them.
+	h.ExpectError(h.Get("/posts/"+post.ID, bobCookie), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestGetPostNotFound(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	_, bob := h.SignUp("bob")
+
+	h.ExpectError(h.Get("/posts/unknown"), http.StatusNotFound, "NOT_FOUND")
+
+	// Posts only visible to followers are hidden from everyone else as if they did not exist.
+	private := h.CreatePost(alice, "followers only", func(post *models.Post) {
+		post.Visibility = models.VisibilityFollowers
+	})
+	h.ExpectError(h.Get("/posts/"+private.ID, bob), http.StatusNotFound, "NOT_FOUND")
+
+	// So are drafts.
+	draft := h.CreatePost(alice, "not yet", func(post *models.Post) {
+		post.Status = models.PostStatusDraft
+	})
+	h.ExpectError(h.Get("/posts/"+draft.ID, bob), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestListPosts(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	h.CreatePost(alice, "first")
+	h.CreatePost(alice, "second")
+	h.CreatePost(alice, "draft", func(post *models.Post) {
+		post.Status = models.PostStatusDraft
+	})
+
+	var list []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts"), http.StatusOK, &list)
+
+	if len(list) != 2 {
+		t.Fatalf("expected the 2 published posts, got %d", len(list))
+	}
+}
+
+func TestCreateReply(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	_, bob := h.SignUp("bob")
+	post := h.CreatePost(alice, "hello world")
+
+	var reply models.Post
+	h.DecodeJSON(h.Post("/posts/"+post.ID+"/replies", map[string]string{"content": "hi alice"}, bob), http.StatusOK, &reply)
+	if reply.ParentID == nil || *reply.ParentID != post.ID {
+		t.Fatalf("expected a reply to %s, got %+v", post.ID, reply)
+	}
+
+	var replies []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts/"+post.ID+"/replies"), http.StatusOK, &replies)
+	if len(replies) != 1 || replies[0].ID != reply.ID {
+		t.Fatalf("expected the reply, got %+v", replies)
+	}
+
+	h.ExpectError(h.Post("/posts/unknown/replies", map[string]string{"content": "hi"}, bob), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestDeletePost(t *testing.T) {
+	h := apptest.New(t)
+	alice, aliceCookie := h.SignUp("alice")
+	_, bobCookie := h.SignUp("bob")
+	post := h.CreatePost(alice, "hello world")
+
+	// Only the author can delete a post.
+	h.ExpectError(h.Delete("/posts/"+post.ID, bobCookie), http.StatusForbidden, "FORBIDDEN")
+
+	h.ExpectStatus(h.Delete("/posts/"+post.ID, aliceCookie), http.StatusOK)
+	h.ExpectError(h.Get("/posts/"+post.ID), http.StatusNotFound, "NOT_FOUND")
+	h.ExpectError(h.Delete("/posts/"+post.ID, aliceCookie), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestDeletePostWindow(t *testing.T) {
+	h := apptest.New(t, func(config *cfg.Configuration) {
+		config.PostDeleteWindow = time.Minute
+	})
+	alice, cookie := h.SignUp("alice")
+
+	post := h.CreatePost(alice, "hello world")
+	if err := h.App.DB.Model(&post).UpdateColumn("created_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h.ExpectError(h.Delete("/posts/"+post.ID, cookie), http.StatusForbidden, "FORBIDDEN")
+}
+
+func TestLikePost(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	_, bob := h.SignUp("bob")
+	post := h.CreatePost(alice, "hello world")
+
+	h.ExpectStatus(h.Post("/posts/"+post.ID+"/likes", nil, bob), http.StatusOK)
+	h.ExpectError(h.Post("/posts/"+post.ID+"/likes", nil, bob), http.StatusConflict, "CONFLICT")
+
+	var likes []models.Like
+	h.DecodeJSON(h.Get("/posts/"+post.ID+"/likes", bob), http.StatusOK, &likes)
+	if len(likes) != 1 || likes[0].LikedBy == nil || likes[0].LikedBy.Username != "bob" {
+		t.Fatalf("expected the like by bob, got %+v", likes)
+	}
+
+	h.ExpectStatus(h.Delete("/posts/"+post.ID+"/likes", bob), http.StatusOK)
+	h.DecodeJSON(h.Get("/posts/"+post.ID+"/likes", bob), http.StatusOK, &likes)
+	if len(likes) != 0 {
+		t.Fatalf("expected no likes after unliking, got %d", len(likes))
+	}
+}
+
+func TestLikePostErrors(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	_, bob := h.SignUp("bob")
+	post := h.CreatePost(alice, "hello world")
+	private := h.CreatePost(alice, "followers only", func(post *models.Post) {
+		post.Visibility = models.VisibilityFollowers
+	})
+
+	h.ExpectError(h.Post("/posts/"+post.ID+"/likes", nil), http.StatusUnauthorized, "UNAUTHORIZED")
+	h.ExpectError(h.Post("/posts/unknown/likes", nil, bob), http.StatusNotFound, "NOT_FOUND")
+	h.ExpectError(h.Post("/posts/"+private.ID+"/likes", nil, bob), http.StatusNotFound, "NOT_FOUND")
+	h.ExpectError(h.Get("/posts/"+private.ID+"/likes", bob), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestPublishDraftOpensPoll(t *testing.T) {
+	h := apptest.New(t)
+	_, cookie := h.SignUp("alice")
+
+	var draft models.Post
+	h.DecodeJSON(h.Post("/posts", map[string]any{
+		"content": "which one?",
+		"draft":   true,
+		"poll": map[string]any{
+			"options":    []string{"this", "that"},
+			"expires_at": time.Now().Add(time.Hour),
+		},
+	}, cookie), http.StatusOK, &draft)
+
+	// The draft sits for longer than its poll would have been open for.
+	if err := h.App.DB.Model(&models.Poll{}).Where("post_id = ?", draft.ID).
+		UpdateColumn("expires_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h.ExpectStatus(h.Post("/posts/"+draft.ID+"/publish", nil, cookie), http.StatusOK)
+
+	var poll models.Poll
+	if err := h.App.DB.Where("post_id = ?", draft.ID).First(&poll).Error; err != nil {
+		t.Fatal(err)
+	}
+	if opensFor := time.Until(poll.ExpiresAt); opensFor < 59*time.Minute || opensFor > time.Hour {
+		t.Errorf("expected the poll to be open for an hour once published, it closes at %s", poll.ExpiresAt)
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	h := apptest.New(t)
+
+	// The replica never catches up, so only reads from the primary see anything.
+	replica, err := db.OpenSQLite("file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := replica.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	h.App.Replicas = []*gorm.DB{replica}
+
+	connection := h.CreateConnection(h.CreateUser("alice"), true)
+	laptop := h.CreateSession(connection)
+	phone := h.CreateSession(connection)
+	_, bob := h.SignUp("bob")
+
+	h.ExpectStatus(h.Post("/posts", map[string]string{"content": "hello world"}, laptop), http.StatusOK)
+
+	// The pin follows the user to their other sessions, not just the client that wrote.
+	var listed []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/posts", phone), http.StatusOK, &listed)
+	if len(listed) != 1 {
+		t.Errorf("expected alice to read her own post from the primary, got %d posts", len(listed))
+	}
+
+	h.DecodeJSON(h.Get("/posts", bob), http.StatusOK, &listed)
+	if len(listed) != 0 {
+		t.Errorf("expected bob to read from the replica, got %d posts", len(listed))
+	}
+}
@@ -0,0 +1,124 @@
+package posts
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// searchLimit is the maximum number of posts returned by a single search.
+const searchLimit = 50
+
+// Search orderings supported by SearchPosts.
+const (
+	SortRelevance = "relevance"
+	SortRecent    = "recent"
+)
+
+// SearchForm is used to parse the query string for post searches.
+type SearchForm struct {
+	Query string `query:"q" validate:"required,max=512"`
+	Sort  string `query:"sort" validate:"omitempty,oneof=relevance recent"`
+}
+
+// searchQuery is the parsed form of a search, with the filters separated from the full-text terms.
+type searchQuery struct {
+	Text     string // Full-text terms, passed to websearch_to_tsquery which handles "phrases" and -exclusions.
+	From     string // Username of the author to restrict the results to.
+	HasLikes bool   // Only return posts that have at least one like.
+}
+
+// parseSearchQuery splits the raw query into its filters and the remaining full-text terms.
+func parseSearchQuery(raw string) searchQuery {
+	var query searchQuery
+	var terms []string
+
+	for _, term := range strings.Fields(raw) {
+		switch {
+		case strings.HasPrefix(term, "from:"):
+			query.From = strings.TrimPrefix(strings.TrimPrefix(term, "from:"), "@")
+		case term == "has:likes":
+			query.HasLikes = true
+		default:
+			terms = append(terms, term)
+		}
+	}
+
+	query.Text = strings.Join(terms, " ")
+	return query
+}
+
+// SearchPosts handles full-text searching of posts with optional author and like filters.
+func (h *Handler) SearchPosts(c *fiber.Ctx) error {
+	// Get the query string and validate it.
+	var form SearchForm
+	if err := utils.ParseQueryAndValidate(c, &form); err != nil {
+		return err
+	}
+
+	query := parseSearchQuery(form.Query)
+
+	// A query made up of filters alone is fine, but there has to be something to search for.
+	if query.Text == "" && query.From == "" && !query.HasLikes {
+		return utils.NewError(fiber.StatusBadRequest, "The search query provided is empty.", &utils.ErrorDetails{
+			Fields: []utils.ErrorField{
+				{
+					Name:   "q",
+					Errors: []string{"The search query provided is empty."},
+				},
+			},
+		})
+	}
+
+	// Get the id of the current user
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	tx := h.DB.
+		Scopes(models.VisibleTo(userID), models.NotMutedBy(userID), repository.PreloadExtended("", userID)).
+		Limit(searchLimit)
+
+	// Match the full-text terms against the generated search column.
+	if query.Text != "" {
+		tx = tx.Where("posts.search @@ websearch_to_tsquery('english', ?)", query.Text)
+	}
+
+	// Restrict the results to a single author.
+	if query.From != "" {
+		tx = tx.Where("posts.author_id IN (?)", h.DB.
+			Model(&models.User{}).
+			Select("id").
+			Where(models.User{Username: strings.ToLower(query.From)}))
+	}
+
+	// Only return posts that have been liked at least once.
+	if query.HasLikes {
+		tx = tx.Where("EXISTS (SELECT 1 FROM likes WHERE likes.post_id = posts.id)")
+	}
+
+	// Order by relevance unless asked otherwise, relevance is meaningless without any terms to rank.
+	if form.Sort != SortRecent && query.Text != "" {
+		tx = tx.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(posts.search, websearch_to_tsquery('english', ?)) DESC, posts.created_at DESC",
+			Vars: []interface{}{query.Text},
+		}})
+	} else {
+		tx = tx.Order("posts.created_at desc")
+	}
+
+	var posts []models.Post
+	if err := tx.Find(&posts).Error; err != nil {
+		return err
+	}
+
+	// Apply the current user's mute filters to the extended posts.
+	extendedPosts, err := filterPosts(h.DB, extendPosts(posts, userID), userID, models.FilterScopeHome)
+	if err != nil {
+		return err
+	}
+
+	// Return the extended version of the posts.
+	return c.JSON(extendedPosts)
+}
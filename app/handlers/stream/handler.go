@@ -0,0 +1,13 @@
+package stream
+
+import "github.com/twibber/core/app"
+
+// Handler serves the event streams.
+type Handler struct {
+	*app.App
+}
+
+// New creates the event stream handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{App: a}
+}
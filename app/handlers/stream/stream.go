@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/pubsub"
+	"github.com/twibber/core/utils"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heartbeatInterval is how often a comment is sent to keep idle connections open through proxies,
+// the session of an authenticated stream is checked again at the same time.
+const heartbeatInterval = 15 * time.Second
+
+// Channels that can be requested through the channels query parameter.
+const (
+	ChannelUser   = "user"   // The current user's notifications and home timeline inserts, requires authentication.
+	ChannelPublic = "public" // The global feed.
+)
+
+// Stream streams events to the client using Server-Sent Events.
+// The channels query parameter selects the channels as a comma separated list, by default the user channel is included
+// when authenticated along with the public channel. Clients resume with the Last-Event-ID header, or the last_event_id
+// query parameter when the header cannot be set.
+func (h *Handler) Stream(c *fiber.Ctx) error {
+	// Get the id of the current user, the stream is authenticated with the existing cookie.
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	// Keep the session the stream was opened with, it is checked again on every heartbeat.
+	sessionID := strings.Clone(c.Cookies(utils.AuthCookieName))
+
+	// Work out which channels were requested.
+	channels := []string{ChannelPublic}
+	if userID != "" {
+		channels = append(channels, ChannelUser)
+	}
+	if requested := c.Query("channels"); requested != "" {
+		channels = strings.Split(requested, ",")
+	}
+
+	// Map the channels onto their topics.
+	var topics []string
+	for _, channel := range channels {
+		switch strings.TrimSpace(channel) {
+		case ChannelPublic:
+			topics = append(topics, pubsub.PublicTopic)
+		case ChannelUser:
+			if userID == "" {
+				return utils.ErrUnauthorised
+			}
+			topics = append(topics, pubsub.UserTopic(userID))
+		default:
+			return utils.NewError(fiber.StatusBadRequest, "One or more of the channels requested do not exist.", &utils.ErrorDetails{
+				Fields: []utils.ErrorField{
+					{
+						Name:   "channels",
+						Errors: []string{"One or more of the channels requested do not exist."},
+					},
+				},
+			})
+		}
+	}
+
+	// Get the ID of the last event the client received, if it is resuming.
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "The last event ID provided is invalid.", nil)
+		}
+		lastID = id
+	}
+
+	subscription := h.Broker.Subscribe(topics, lastID)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Stop reverse proxies from buffering the stream.
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer subscription.Close()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		// Tell the client how long to wait before reconnecting.
+		fmt.Fprint(w, "retry: 3000\n\n")
+
+		// Send the events missed since the client last connected.
+		for _, event := range subscription.Replay {
+			writeEvent(w, event)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-subscription.Events:
+				// The broker closed the subscription, such as when the server shuts down, the client can reconnect and resume.
+				if !ok {
+					return
+				}
+				writeEvent(w, event)
+			case <-heartbeat.C:
+				// End the stream once its session has expired or been revoked, such as by logging out.
+				if userID != "" && !h.sessionActive(sessionID) {
+					return
+				}
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+
+			// A failed flush means the client has disconnected.
+			if err := w.Flush(); err != nil {
+				slog.With("error", err).Debug("stream client disconnected")
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// sessionActive returns whether the session still exists and has not expired.
+func (h *Handler) sessionActive(sessionID string) bool {
+	var count int64
+	if err := h.DB.Model(&models.Session{}).
+		Where("id = ? AND expires_at > ?", sessionID, time.Now()).
+		Count(&count).Error; err != nil {
+		slog.With("error", err).Error("failed to check the session of a stream")
+		return false
+	}
+
+	return count > 0
+}
+
+// writeEvent writes a single event in the Server-Sent Events format.
+func writeEvent(w *bufio.Writer, event pubsub.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
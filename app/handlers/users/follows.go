@@ -0,0 +1,116 @@
+package users
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/handlers/notifications"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+)
+
+// FollowUser handles following the specified user.
+// Protected accounts have to approve their followers, so a follow request is created for them instead.
+func (h *Handler) FollowUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is following.
+	followerID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being followed by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	if user.ID == followerID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot follow yourself.", nil)
+	}
+
+	// Users cannot follow each other while either has blocked the other.
+	var blocks int64
+	if err := h.DB.Model(&models.Block{}).
+		Scopes(models.BlockBetween(followerID, user.ID)).
+		Count(&blocks).Error; err != nil {
+		return err
+	}
+
+	if blocks > 0 {
+		return utils.NewError(fiber.StatusForbidden, "You cannot follow this user.", nil)
+	}
+
+	// Check if the user is already followed.
+	var count int64
+	if err := h.DB.Model(&models.Follow{}).Where(models.Follow{
+		FollowerID:  followerID,
+		FollowingID: user.ID,
+	}).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return utils.NewError(fiber.StatusConflict, "You are already following this user.", nil)
+	}
+
+	// Protected accounts approve their followers, so request to follow them instead.
+	if user.Protected {
+		if err := h.DB.Create(&models.FollowRequest{
+			RequesterID: followerID,
+			TargetID:    user.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Notify the user of the request.
+		h.notifier.Notify(notifications.Event{
+			Type:        models.NotificationFollowRequest,
+			RecipientID: user.ID,
+			ActorID:     followerID,
+		})
+
+		return c.SendStatus(fiber.StatusAccepted)
+	}
+
+	// Create the follow.
+	if err := h.DB.Create(&models.Follow{
+		FollowerID:  followerID,
+		FollowingID: user.ID,
+	}).Error; err != nil {
+		return err
+	}
+
+	// Notify the user of their new follower.
+	h.notifier.Notify(notifications.Event{
+		Type:        models.NotificationFollow,
+		RecipientID: user.ID,
+		ActorID:     followerID,
+	})
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnfollowUser handles unfollowing the specified user, this also cancels any pending follow request.
+func (h *Handler) UnfollowUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is unfollowing.
+	followerID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being unfollowed by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	// Delete the follow.
+	if err := h.DB.Where(models.Follow{
+		FollowerID:  followerID,
+		FollowingID: user.ID,
+	}).Delete(&models.Follow{}).Error; err != nil {
+		return err
+	}
+
+	// Cancel any pending follow request.
+	if err := h.DB.Where(models.FollowRequest{
+		RequesterID: followerID,
+		TargetID:    user.ID,
+	}).Delete(&models.FollowRequest{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
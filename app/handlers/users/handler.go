@@ -0,0 +1,21 @@
+package users
+
+import (
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/notifications"
+)
+
+// Handler serves the user endpoints.
+type Handler struct {
+	*app.App
+
+	notifier *notifications.Notifier
+}
+
+// New creates the user handlers for the application.
+func New(a *app.App) *Handler {
+	return &Handler{
+		App:      a,
+		notifier: notifications.NewNotifier(a.DB, a.Broker),
+	}
+}
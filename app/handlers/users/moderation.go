@@ -0,0 +1,120 @@
+package users
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+)
+
+// BlockUser handles blocking the specified user, removing any follow or follow request between the two users.
+func (h *Handler) BlockUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is blocking.
+	blockerID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being blocked by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	if user.ID == blockerID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot block yourself.", nil)
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Create the block, blocking a user twice is a conflict.
+		if err := tx.Create(&models.Block{
+			BlockerID: blockerID,
+			BlockedID: user.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Remove any follow in both directions.
+		if err := tx.
+			Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)", blockerID, user.ID, user.ID, blockerID).
+			Delete(&models.Follow{}).Error; err != nil {
+			return err
+		}
+
+		// Remove any pending follow request in both directions.
+		return tx.
+			Where("(requester_id = ? AND target_id = ?) OR (requester_id = ? AND target_id = ?)", blockerID, user.ID, user.ID, blockerID).
+			Delete(&models.FollowRequest{}).Error
+	}); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnblockUser handles unblocking the specified user, removed follows are not restored.
+func (h *Handler) UnblockUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is unblocking.
+	blockerID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being unblocked by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	// Delete the block.
+	if err := h.DB.Where(models.Block{
+		BlockerID: blockerID,
+		BlockedID: user.ID,
+	}).Delete(&models.Block{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// MuteUser handles muting the specified user, filtering them out of the current user's timelines and notifications.
+func (h *Handler) MuteUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is muting.
+	muterID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being muted by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	if user.ID == muterID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot mute yourself.", nil)
+	}
+
+	// Create the mute, muting a user twice is a conflict.
+	if err := h.DB.Create(&models.Mute{
+		MuterID: muterID,
+		MutedID: user.ID,
+	}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnmuteUser handles unmuting the specified user.
+func (h *Handler) UnmuteUser(c *fiber.Ctx) error {
+	// Get the current session of the user that is unmuting.
+	muterID := c.Locals("session").(models.Session).Connection.UserID
+
+	// Get the user being unmuted by their username.
+	user, err := h.Repos.Users.FindByUsername(c.Params("user"))
+	if err != nil {
+		return err
+	}
+
+	// Delete the mute.
+	if err := h.DB.Where(models.Mute{
+		MuterID: muterID,
+		MutedID: user.ID,
+	}).Delete(&models.Mute{}).Error; err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,62 @@
+package users
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// defaultSearchLimit is the number of users returned when no limit is provided.
+const defaultSearchLimit = 10
+
+// SearchForm is used to parse the query string for user searches.
+type SearchForm struct {
+	Query string `query:"q" validate:"required,max=64"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=25"`
+}
+
+// likeEscaper escapes the wildcard characters of a LIKE pattern so user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers handles typeahead searches of users by their username and display name.
+// Results are ranked by whether the current user follows them, and then by trigram similarity.
+func (h *Handler) SearchUsers(c *fiber.Ctx) error {
+	// Get the query string and validate it.
+	var form SearchForm
+	if err := utils.ParseQueryAndValidate(c, &form); err != nil {
+		return err
+	}
+
+	if form.Limit == 0 {
+		form.Limit = defaultSearchLimit
+	}
+
+	// Usernames are always lowercase, so compare everything in lowercase.
+	query := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(form.Query, "@")))
+	prefix := likeEscaper.Replace(query) + "%"
+
+	// Get the id of the current user, empty if the request is not authenticated.
+	userID := utils.GetUserID(c, h.Repos.Sessions)
+
+	var users []models.User
+	if err := h.DB.
+		Omit("Email"). // Omit the email field for security and privacy reasons
+		// Match the start of the username, the start of the display name or the start of any word in the display name.
+		Where("users.username LIKE ? OR lower(users.display_name) LIKE ? OR lower(users.display_name) LIKE ?", prefix, prefix, "% "+prefix).
+		// Also match close misspellings of the username.
+		Or("users.username % ?", query).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL: "EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = ? AND follows.following_id = users.id) DESC, " +
+				"GREATEST(similarity(users.username, ?), similarity(lower(users.display_name), ?)) DESC, " +
+				"users.username ASC",
+			Vars: []interface{}{userID, query, query},
+		}}).
+		Limit(form.Limit).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(users)
+}
@@ -2,31 +2,51 @@ package users
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app/handlers/posts"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
+	"github.com/twibber/core/app/repository"
+	"github.com/twibber/core/utils"
 )
 
-func ListUsers(c *fiber.Ctx) error {
-	var users []models.User
-	if err := db.DB.
-		Omit("Email"). // Omit the email field for security and privacy reasons
-		Find(&users).Error; err != nil {
+func (h *Handler) ListUsers(c *fiber.Ctx) error {
+	users, err := h.Repos.Users.List()
+	if err != nil {
 		return err
 	}
 
 	return c.JSON(users)
 }
 
-// GetUser returns the specified user's profile
-func GetUser(c *fiber.Ctx) error {
-	// Get user using the ID provided in the request
-	var user models.User
-	if err := db.DB.
-		// Where(&models.User{BaseModel: models.BaseModel{ID: c.Params("user")}}).
-		Where(models.User{Username: c.Params("user")}).
-		First(&user).Error; err != nil {
+// Profile represents a user's profile along with their pinned posts.
+type Profile struct {
+	models.User
+
+	Pinned []posts.ExtendedPost `json:"pinned"` // Pinned posts visible to the current user, in the order they were pinned.
+}
+
+// GetUser returns the specified user's basic profile, this is always visible even for protected accounts
+func (h *Handler) GetUser(c *fiber.Ctx) error {
+	// Read from a replica, unless the current user has just written.
+	reader := h.Read(c)
+
+	// Get user using the username provided in the request
+	user, err := repository.New(reader).Users.FindByUsername(c.Params("user"))
+	if err != nil {
 		return err
 	}
 
-	return c.JSON(user)
+	// Get the pinned posts, they are hidden from anyone who cannot see them like any other post.
+	pinned, err := posts.ListPinned(reader, user.ID, utils.GetUserID(c, h.Repos.Sessions))
+	if err != nil {
+		return err
+	}
+
+	if pinned == nil {
+		pinned = []posts.ExtendedPost{}
+	}
+
+	return c.JSON(Profile{
+		User:   user,
+		Pinned: pinned,
+	})
 }
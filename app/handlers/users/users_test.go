@@ -0,0 +1,80 @@
+package users_test
+
+import (
+	"github.com/twibber/core/app/apptest"
+	"github.com/twibber/core/app/handlers/posts"
+	"github.com/twibber/core/app/handlers/users"
+	"github.com/twibber/core/app/models"
+	"net/http"
+	"testing"
+)
+
+func TestListUsers(t *testing.T) {
+	h := apptest.New(t)
+	h.CreateUser("alice")
+	h.CreateUser("bob")
+
+	var list []models.User
+	h.DecodeJSON(h.Get("/users"), http.StatusOK, &list)
+
+	if len(list) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(list))
+	}
+	for _, user := range list {
+		if user.Email != "" {
+			t.Errorf("expected the email of %s to be hidden, got %s", user.Username, user.Email)
+		}
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	h := apptest.New(t)
+	alice, cookie := h.SignUp("alice")
+	post := h.CreatePost(alice, "pinned")
+	h.ExpectStatus(h.Post("/posts/"+post.ID+"/pin", nil, cookie), http.StatusOK)
+
+	var profile users.Profile
+	h.DecodeJSON(h.Get("/users/alice"), http.StatusOK, &profile)
+
+	if profile.ID != alice.ID || profile.Username != "alice" {
+		t.Fatalf("expected the profile of alice, got %+v", profile.User)
+	}
+	if profile.Email != "" {
+		t.Errorf("expected the email to be hidden, got %s", profile.Email)
+	}
+	if len(profile.Pinned) != 1 || profile.Pinned[0].ID != post.ID {
+		t.Errorf("expected the pinned post, got %+v", profile.Pinned)
+	}
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	h := apptest.New(t)
+
+	h.ExpectError(h.Get("/users/nobody"), http.StatusNotFound, "NOT_FOUND")
+	h.ExpectError(h.Get("/users/nobody/posts"), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestGetUserPosts(t *testing.T) {
+	h := apptest.New(t)
+	alice := h.CreateUser("alice")
+	bob, bobCookie := h.SignUp("bob")
+
+	h.CreatePost(alice, "public")
+	h.CreatePost(alice, "followers only", func(post *models.Post) {
+		post.Visibility = models.VisibilityFollowers
+	})
+	h.CreatePost(bob, "not alice's")
+
+	var list []posts.ExtendedPost
+	h.DecodeJSON(h.Get("/users/alice/posts", bobCookie), http.StatusOK, &list)
+	if len(list) != 1 || list[0].Content != "public" {
+		t.Fatalf("expected only the public post, got %+v", list)
+	}
+
+	// Once bob follows alice, the posts for followers are visible too.
+	h.ExpectStatus(h.Post("/users/alice/follow", nil, bobCookie), http.StatusOK)
+	h.DecodeJSON(h.Get("/users/alice/posts", bobCookie), http.StatusOK, &list)
+	if len(list) != 2 {
+		t.Fatalf("expected both posts once following, got %d", len(list))
+	}
+}
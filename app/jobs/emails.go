@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+const (
+	// sendInterval is how often the outbox is checked for due emails.
+	sendInterval = 15 * time.Second
+
+	// emailBatchSize is the most emails claimed by a single run.
+	emailBatchSize = 10
+
+	// emailClaimTimeout is how long claimed emails are hidden from other instances while they are sent,
+	// it is much longer than a single attempt can take, see MAIL_TIMEOUT.
+	// If the instance stops before it finishes, the emails are sent again once it has passed.
+	emailClaimTimeout = 5 * time.Minute
+)
+
+var (
+	// SendOutboundEmails sends the emails in the outbox that are due, retrying failed ones with exponential backoff,
+	// until they have failed MAIL_MAX_ATTEMPTS times and are dead, waiting for an operator to retry them.
+	SendOutboundEmails = queue.NewKind[struct{}]("emails.send_outbound")
+	// PurgeSentEmails deletes the emails that were sent longer ago than MAIL_RETENTION.
+	PurgeSentEmails = queue.NewKind[struct{}]("emails.purge_sent")
+)
+
+func EmailJobs(q *queue.Queue, a *app.App) {
+	queue.Handle(q, SendOutboundEmails, func(ctx context.Context, _ struct{}) error {
+		return sendOutboundEmails(ctx, a)
+	}, queue.Timeout(emailClaimTimeout))
+
+	queue.Handle(q, PurgeSentEmails, func(ctx context.Context, _ struct{}) error {
+		if a.Config.MailRetention == 0 {
+			return nil
+		}
+
+		return a.DB.WithContext(ctx).
+			Where("status = ? AND sent_at < ?", models.OutboundEmailSent, time.Now().Add(-a.Config.MailRetention)).
+			Delete(&models.OutboundEmail{}).Error
+	})
+
+	queue.Every(q, SendOutboundEmails, sendInterval, struct{}{})
+	queue.Recurring(q, PurgeSentEmails, "@hourly", struct{}{})
+}
+
+// sendOutboundEmails claims a batch of due emails and sends them one at a time.
+//
+// The due emails are claimed with a single UPDATE that skips the rows other instances have locked,
+// so when several instances run at once each email is only sent by one of them. The claim is renewed before each
+// attempt, so a slow mail server cannot let the claim on the rest of the batch run out while they wait.
+// If the server shuts down part way through, the emails not sent yet are released to be sent straight away.
+func sendOutboundEmails(ctx context.Context, a *app.App) error {
+	now := time.Now()
+
+	due := a.DB.
+		Model(&models.OutboundEmail{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.OutboundEmailPending, now).
+		Order("next_attempt_at asc").
+		Limit(emailBatchSize).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var emails []models.OutboundEmail
+	if err := a.DB.WithContext(ctx).
+		Model(&emails).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(emailClaimTimeout),
+		}).Error; err != nil {
+		return err
+	}
+
+	for i, email := range emails {
+		if ctx.Err() != nil {
+			return releaseEmails(a.DB, emails[i:])
+		}
+
+		log := slog.With("email", email.ID, "template", email.Template, "attempts", email.Attempts)
+
+		// Renew the claim, unless it already ran out and another instance claimed the email, making it theirs to send.
+		claim := a.DB.
+			Model(&email).
+			Where("status = ? AND attempts = ?", models.OutboundEmailPending, email.Attempts).
+			UpdateColumn("next_attempt_at", time.Now().Add(emailClaimTimeout))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			log.Warn("email was claimed by another instance")
+			continue
+		}
+
+		updates := map[string]interface{}{
+			"status":     models.OutboundEmailSent,
+			"sent_at":    time.Now(),
+			"last_error": "",
+		}
+
+		if err := a.Mailer.SendOutbound(a.DB, email); err != nil {
+			updates = map[string]interface{}{"last_error": err.Error()}
+
+			if email.Attempts >= a.Config.MailMaxAttempts {
+				updates["status"] = models.OutboundEmailDead
+				log.With("error", err).Error("gave up sending email, retry it with the outbox command")
+			} else {
+				retryAt := time.Now().Add(a.Mailer.RetryDelay(email.Attempts))
+				updates["next_attempt_at"] = retryAt
+				log.With("error", err, "retry_at", retryAt).Warn("failed to send email")
+			}
+		} else {
+			log.Debug("sent email")
+		}
+
+		// The outcome is recorded even while shutting down, so a sent email is never sent again.
+		if err := a.DB.Model(&email).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseEmails puts claimed emails that were not attempted back in the outbox, without counting the attempt.
+func releaseEmails(db *gorm.DB, emails []models.OutboundEmail) error {
+	var ids []string
+	for _, email := range emails {
+		ids = append(ids, email.ID)
+	}
+
+	slog.With("emails", len(ids)).Warn("released emails not sent before shutdown")
+	return db.Model(&models.OutboundEmail{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts - 1"),
+			"next_attempt_at": time.Now(),
+		}).Error
+}
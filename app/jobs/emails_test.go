@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"github.com/twibber/core/app/apptest"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/mail"
+	"testing"
+	"time"
+)
+
+// queueEmails queues a verification email for each of the users, returning the emails in the outbox.
+func queueEmails(t *testing.T, h *apptest.Harness, usernames ...string) []models.OutboundEmail {
+	t.Helper()
+
+	for _, username := range usernames {
+		connection := h.CreateConnection(h.CreateUser(username), false)
+		if err := (mail.VerifyDTO{
+			Defaults:     mail.Defaults{Email: username + "@example.com", Name: username},
+			ConnectionID: connection.ID,
+		}).Queue(h.App.DB, h.App.Mailer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return outbox(t, h)
+}
+
+// outbox gets every email in the outbox.
+func outbox(t *testing.T, h *apptest.Harness) []models.OutboundEmail {
+	t.Helper()
+
+	var emails []models.OutboundEmail
+	if err := h.App.DB.Order("recipient").Find(&emails).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return emails
+}
+
+func TestSendOutboundEmails(t *testing.T) {
+	h := apptest.New(t)
+	queueEmails(t, h, "alice", "bob")
+
+	if err := sendOutboundEmails(context.Background(), h.App); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range outbox(t, h) {
+		if email.Status != models.OutboundEmailSent || email.Attempts != 1 || email.SentAt == nil {
+			t.Errorf("expected %s to be sent on the first attempt, got %s after %d", email.Recipient, email.Status, email.Attempts)
+		}
+	}
+}
+
+func TestReleaseEmails(t *testing.T) {
+	h := apptest.New(t)
+	queueEmails(t, h, "alice", "bob")
+
+	// The emails were claimed, but the server shut down before they were attempted.
+	if err := h.App.DB.Model(&models.OutboundEmail{}).Where("1 = 1").Updates(map[string]interface{}{
+		"attempts":        1,
+		"next_attempt_at": time.Now().Add(emailClaimTimeout),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := releaseEmails(h.App.DB, outbox(t, h)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range outbox(t, h) {
+		if email.Status != models.OutboundEmailPending || email.Attempts != 0 || email.NextAttemptAt.After(time.Now()) {
+			t.Errorf("expected %s to be released, got %s after %d attempts due at %s", email.Recipient, email.Status, email.Attempts, email.NextAttemptAt)
+		}
+	}
+}
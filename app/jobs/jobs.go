@@ -0,0 +1,21 @@
+// Package jobs registers the background jobs of the application with the job queue,
+// in the same way the routes register the handlers of the endpoints.
+package jobs
+
+import (
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/queue"
+)
+
+// Configure creates the job queue with the handlers of every kind of job and the recurring jobs.
+func Configure(a *app.App) *queue.Queue {
+	q := queue.New(a.DB, a.Config)
+
+	// Initiate the jobs of each area
+	PostJobs(q, a)
+	EmailJobs(q, a)
+	SessionJobs(q, a)
+
+	// Return the configured queue to be started
+	return q
+}
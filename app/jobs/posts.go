@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/notifications"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+// publishInterval is how often scheduled posts are checked, so they go public at most this long after their time.
+const publishInterval = 15 * time.Second
+
+// PublishDuePosts publishes every scheduled post whose publish time has passed.
+var PublishDuePosts = queue.NewKind[struct{}]("posts.publish_due")
+
+func PostJobs(q *queue.Queue, a *app.App) {
+	notifier := notifications.NewNotifier(a.DB, a.Broker)
+
+	queue.Handle(q, PublishDuePosts, func(ctx context.Context, _ struct{}) error {
+		var due []models.Post
+		if err := a.DB.WithContext(ctx).
+			Select("id").
+			Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+			Order("publish_at ASC").
+			Find(&due).Error; err != nil {
+			return err
+		}
+
+		// Each post is finished once it is public, even if the server is shutting down, the rest wait for the next run.
+		for _, post := range due {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			published, err := publishDuePost(a.DB, post.ID)
+			if err != nil {
+				slog.With("post", post.ID, "error", err).Error("failed to publish scheduled post")
+				continue
+			}
+
+			// Another instance may have published the post first.
+			if published == nil {
+				continue
+			}
+
+			slog.With("post", published.ID, "author", published.AuthorID).Debug("published scheduled post")
+
+			// Notify the author of the post being replied to, now that the reply is public.
+			notifier.NotifyReply(*published)
+
+			// Push the post to the streams of the global feed and the author's followers, or the thread it replies to.
+			a.Events.PublishPost(*published)
+			a.Events.PublishReply(*published)
+		}
+
+		return nil
+	})
+
+	queue.Every(q, PublishDuePosts, publishInterval, struct{}{})
+}
+
+// publishDuePost publishes a scheduled post and opens its poll in a single transaction, it returns nil when
+// the post was already published.
+//
+// The post is published with a conditional UPDATE, so when several instances run at once
+// Postgres' row locks make sure each post is only ever published by one of them.
+func publishDuePost(db *gorm.DB, postID string) (*models.Post, error) {
+	var posts []models.Post
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(&posts).
+			Clauses(clause.Returning{}).
+			Where("id = ? AND status = ?", postID, models.PostStatusScheduled).
+			Updates(map[string]interface{}{
+				"status":     models.PostStatusPublished,
+				"created_at": gorm.Expr("publish_at"), // the post was created when it went public
+				"publish_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		if len(posts) == 0 {
+			return nil
+		}
+
+		// The poll closes its duration after the time the post went public.
+		return models.OpenPoll(tx, postID, posts[0].CreatedAt)
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(posts) == 0 {
+		return nil, nil
+	}
+
+	return &posts[0], nil
+}
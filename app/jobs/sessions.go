@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/queue"
+	"log/slog"
+	"time"
+)
+
+// PurgeExpiredSessions deletes the sessions that have expired, they can no longer be used to sign in.
+var PurgeExpiredSessions = queue.NewKind[struct{}]("sessions.purge_expired")
+
+func SessionJobs(q *queue.Queue, a *app.App) {
+	queue.Handle(q, PurgeExpiredSessions, func(ctx context.Context, _ struct{}) error {
+		result := a.DB.WithContext(ctx).
+			Where("expires_at < ?", time.Now()).
+			Delete(&models.Session{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		slog.With("sessions", result.RowsAffected).Debug("purged expired sessions")
+		return nil
+	})
+
+	queue.Recurring(q, PurgeExpiredSessions, "@hourly", struct{}{})
+}
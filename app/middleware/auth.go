@@ -2,14 +2,24 @@ package middleware
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/db"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/utils"
 	"log/slog"
 	"time"
 )
 
-func Auth(verify bool) fiber.Handler {
+// Middleware provides the middleware that depends on the application, such as authentication.
+type Middleware struct {
+	*app.App
+}
+
+// New creates the middleware for the application.
+func New(a *app.App) *Middleware {
+	return &Middleware{App: a}
+}
+
+// Auth requires a valid session, and a verified account when verify is set, attaching the session to the context.
+func (m *Middleware) Auth(verify bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authCookie := c.Cookies(utils.AuthCookieName)
 
@@ -19,18 +29,10 @@ func Auth(verify bool) fiber.Handler {
 			return utils.ErrUnauthorised
 		}
 
-		var session models.Session
-		if err := db.DB.Where(models.Session{
-			BaseModel: models.BaseModel{
-				ID: authCookie,
-			},
-		}).
-			Preload("Connection").
-			Preload("Connection.User").
-			First(&session).
-			Error; err != nil {
+		session, err := m.Repos.Sessions.Find(authCookie)
+		if err != nil {
 			// even if it is another error, we will return unauthorised and clear the cookie
-			utils.ClearAuth(c)
+			utils.ClearAuth(c, m.Config.Domain)
 			return utils.ErrUnauthorised
 		}
 
@@ -38,7 +40,7 @@ func Auth(verify bool) fiber.Handler {
 		if time.Now().After(session.ExpiresAt) {
 			slog.With("session", session).Debug("Session expired")
 
-			utils.ClearAuth(c)
+			utils.ClearAuth(c, m.Config.Domain)
 			return utils.ErrUnauthorised
 		}
 
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/utils"
+	"log/slog"
+)
+
+// ReadYourWrites pins the reads of a user to the primary database for a short window after they write,
+// so they always see their own writes even while the read replicas are behind.
+// The pin is kept on every session of the user rather than the client, so it holds for all of their sessions
+// and whichever instance serves the next request, see App.Read.
+func (m *Middleware) ReadYourWrites() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		// Without replicas every read already comes from the primary.
+		if len(m.Replicas) == 0 || m.Config.DBPrimaryPinWindow == 0 {
+			return err
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return err
+		}
+
+		// Only successful requests by signed in users write anything worth reading back.
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			return err
+		}
+
+		userID := utils.GetUserID(c, m.Repos.Sessions)
+		if userID == "" {
+			return nil
+		}
+
+		// The write already happened, so failing to pin only risks a stale read.
+		if err := m.PinToPrimary(userID); err != nil {
+			slog.With("user", userID, "error", err).Error("failed to pin the user to the primary database")
+		}
+
+		return nil
+	}
+}
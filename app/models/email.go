@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// OutboundEmailStatus represents the delivery state of an outbound email.
+type OutboundEmailStatus string
+
+const (
+	OutboundEmailPending OutboundEmailStatus = "pending" // Waiting to be sent, or to be retried once NextAttemptAt has passed.
+	OutboundEmailSent    OutboundEmailStatus = "sent"    // Accepted by the mail server.
+	OutboundEmailDead    OutboundEmailStatus = "dead"    // Every attempt failed, it is only sent again if an operator retries it.
+)
+
+// OutboundEmail is an email waiting in the outbox, it is created in the same transaction as the change that sends it,
+// so the email is never lost when the mail server is down, nor sent for a change that was rolled back.
+// The email is rendered from its template when it is sent.
+type OutboundEmail struct {
+	BaseModel
+
+	Recipient string `gorm:"size:255;not null" json:"recipient"`
+	Subject   string `gorm:"size:512;not null" json:"subject"`
+	Template  string `gorm:"size:64;not null" json:"template"`
+	Data      string `gorm:"type:text;not null" json:"-"` // The template data as JSON, codes are generated as the email is sent and never stored.
+
+	Status        OutboundEmailStatus `gorm:"size:16;not null;default:pending;index:idx_outbound_emails_status_next_attempt_at" json:"status"`
+	Attempts      int                 `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time           `gorm:"not null;index:idx_outbound_emails_status_next_attempt_at" json:"next_attempt_at"`
+	LastError     string              `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time          `json:"sent_at,omitempty"`
+}
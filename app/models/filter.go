@@ -0,0 +1,62 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"regexp"
+	"time"
+)
+
+// FilterScope represents where a mute filter is applied.
+type FilterScope string
+
+const (
+	FilterScopeHome          FilterScope = "home"          // Timelines, such as the global feed, profiles and search.
+	FilterScopeReplies       FilterScope = "replies"       // Replies to a post.
+	FilterScopeNotifications FilterScope = "notifications" // Notifications about posts.
+	FilterScopeAll           FilterScope = "all"           // Everywhere.
+)
+
+// FilterAction represents what happens to a post matched by a mute filter.
+type FilterAction string
+
+const (
+	FilterActionHide FilterAction = "hide" // The post is dropped from the response.
+	FilterActionWarn FilterAction = "warn" // The post is returned with the filter that matched it.
+)
+
+// MuteFilter represents a word, phrase or hashtag a user does not want to see.
+type MuteFilter struct {
+	BaseModel
+
+	UserID string `gorm:"not null;index" json:"-"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Keyword is matched case-insensitively as a whole word, phrase or hashtag, never as part of a longer word.
+	Keyword string       `gorm:"size:128;not null" json:"keyword"`
+	Scope   FilterScope  `gorm:"size:16;not null;default:all" json:"scope"`
+	Action  FilterAction `gorm:"size:16;not null;default:hide" json:"action"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // The filter stops applying after this time, if set.
+
+	// pattern is the compiled keyword, it is compiled on the first match and reused for every post after.
+	pattern *regexp.Regexp
+}
+
+// Matches returns whether the content contains the filter's keyword.
+func (f *MuteFilter) Matches(content string) bool {
+	if f.pattern == nil {
+		f.pattern = regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(f.Keyword) + `($|\W)`)
+	}
+
+	return f.pattern.MatchString(content)
+}
+
+// ActiveFilters is a GORM scope on mute filters that returns the user's filters which have not expired and apply to the scope.
+func ActiveFilters(userID string, scope FilterScope) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.
+			Where("mute_filters.user_id = ?", userID).
+			Where("mute_filters.scope IN ?", []FilterScope{scope, FilterScopeAll}).
+			Where("mute_filters.expires_at IS NULL OR mute_filters.expires_at > ?", time.Now())
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// JobStatus represents the state of a background job.
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending" // Waiting to run once RunAt has passed, including jobs waiting to be retried.
+	JobRunning JobStatus = "running" // Claimed by a worker until LockedUntil, after which another worker can claim it.
+	JobDead    JobStatus = "dead"    // Every attempt failed, it is kept for an operator to look into.
+)
+
+// Job is a unit of background work in the job queue, jobs that succeed are deleted.
+// Only one pending job can have a unique key, so enqueueing a job whose key is already waiting does nothing.
+type Job struct {
+	BaseModel
+
+	Kind      string  `gorm:"size:128;not null" json:"kind"`
+	Payload   string  `gorm:"type:text;not null" json:"payload"` // The payload as JSON, decoded into the type the kind's handler takes.
+	UniqueKey *string `gorm:"size:255;uniqueIndex:idx_jobs_unique_key,where:status = 'pending'" json:"unique_key,omitempty"`
+
+	Status      JobStatus  `gorm:"size:16;not null;default:pending;index:idx_jobs_status_run_at" json:"status"`
+	RunAt       time.Time  `gorm:"not null;index:idx_jobs_status_run_at" json:"run_at"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:5" json:"max_attempts"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+}
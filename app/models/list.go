@@ -0,0 +1,41 @@
+package models
+
+import "gorm.io/gorm"
+
+// List represents a named list of accounts curated by a user, with a timeline of their posts.
+type List struct {
+	BaseModel
+
+	OwnerID string `gorm:"not null;index" json:"owner_id"`
+	Owner   *User  `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:CASCADE" json:"owner,omitempty"`
+
+	Name        string `gorm:"size:64;not null" json:"name"`
+	Description string `gorm:"size:256" json:"description"`
+
+	// Private lists, along with their members and timeline, are only visible to their owner.
+	Private bool `gorm:"not null;default:false" json:"private"`
+
+	Members []ListMember `gorm:"foreignKey:ListID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ListMember represents an account added to a list.
+type ListMember struct {
+	BaseModel
+
+	ListID string `gorm:"not null;uniqueIndex:idx_list_members_list_user" json:"-"`
+	List   *List  `gorm:"foreignKey:ListID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID string `gorm:"not null;uniqueIndex:idx_list_members_list_user;index" json:"user_id"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// ListVisibleTo is a GORM scope that only returns the lists the viewer is allowed to see.
+// Owners can always see their lists, everyone else can only see public lists of owners they have no block with.
+func ListVisibleTo(viewerID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(`lists.owner_id = ? OR (
+			lists.private = ?
+			AND NOT EXISTS (SELECT 1 FROM blocks WHERE (blocks.blocker_id = ? AND blocks.blocked_id = lists.owner_id) OR (blocks.blocker_id = lists.owner_id AND blocks.blocked_id = ?))
+		)`, viewerID, false, viewerID, viewerID)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Conversation represents a private conversation between two or more users.
+type Conversation struct {
+	BaseModel
+
+	// DirectKey identifies a one to one conversation by its two users, so there is only ever one between them.
+	// It is nil for group conversations.
+	DirectKey *string `gorm:"size:128;uniqueIndex" json:"-"`
+	Name      string  `gorm:"size:64" json:"name,omitempty"` // Optional name of a group conversation.
+
+	Members  []ConversationMember `gorm:"foreignKey:ConversationID;references:ID;constraint:OnDelete:CASCADE" json:"members,omitempty"`
+	Messages []Message            `gorm:"foreignKey:ConversationID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	LastMessageAt time.Time `gorm:"not null;index" json:"last_message_at"` // Time of the most recent message, used for ordering.
+}
+
+// Direct returns whether the conversation is a one to one conversation.
+func (c *Conversation) Direct() bool {
+	return c.DirectKey != nil
+}
+
+// DirectKey builds the key of the one to one conversation between the two users, regardless of their order.
+func DirectKey(userID, otherID string) string {
+	ids := []string{userID, otherID}
+	sort.Strings(ids)
+	return strings.Join(ids, ":")
+}
+
+// ConversationMember represents a user taking part in a conversation.
+// Leaving a conversation deletes the member, rejoining creates a new member so the earlier messages stay hidden.
+type ConversationMember struct {
+	BaseModel
+
+	ConversationID string        `gorm:"not null;uniqueIndex:idx_conversation_members_conversation_user" json:"-"`
+	Conversation   *Conversation `gorm:"foreignKey:ConversationID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID string `gorm:"not null;uniqueIndex:idx_conversation_members_conversation_user;index" json:"user_id"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+
+	JoinedAt   time.Time  `gorm:"not null" json:"joined_at"` // Messages sent before the member joined are hidden from them.
+	LastReadAt *time.Time `json:"last_read_at"`              // Messages sent after this are unread.
+}
+
+// Message represents a single message sent in a conversation.
+type Message struct {
+	BaseModel
+
+	ConversationID string        `gorm:"not null;index" json:"conversation_id"`
+	Conversation   *Conversation `gorm:"foreignKey:ConversationID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	SenderID string `gorm:"not null" json:"sender_id"`
+	Sender   *User  `gorm:"foreignKey:SenderID;references:ID;constraint:OnDelete:CASCADE" json:"sender,omitempty"`
+
+	Content string `gorm:"size:1000;not null" json:"content"`
+
+	// Deletions are private to the user who deleted the message, so they are never serialised.
+	Deletions []MessageDeletion `gorm:"foreignKey:MessageID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// MessageDeletion represents a message deleted by a user for themselves, it remains visible to everyone else.
+type MessageDeletion struct {
+	BaseModel
+
+	MessageID string   `gorm:"not null;uniqueIndex:idx_message_deletions_message_user" json:"message_id"`
+	Message   *Message `gorm:"foreignKey:MessageID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID string `gorm:"not null;uniqueIndex:idx_message_deletions_message_user" json:"-"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// MessagesVisibleTo is a GORM scope on messages that only returns the messages of a conversation the member can see,
+// those sent since they joined that they have not deleted for themselves.
+func MessagesVisibleTo(member ConversationMember) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.
+			Where("messages.conversation_id = ? AND messages.created_at >= ?", member.ConversationID, member.JoinedAt).
+			Where("NOT EXISTS (SELECT 1 FROM message_deletions WHERE message_deletions.message_id = messages.id AND message_deletions.user_id = ?)", member.UserID)
+	}
+}
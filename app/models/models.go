@@ -8,12 +8,35 @@ import (
 )
 
 // Models is a slice of all the models in the application.
+// The schema itself is created by the migrations in db/migrations, which must be kept in step with the models.
 var Models = []interface{}{
 	&User{},
+	&Follow{},
+	&FollowRequest{},
+	&Block{},
+	&Mute{},
+	&MuteFilter{},
+	&Notification{},
+	&NotificationActor{},
 	&Connection{},
 	&Session{},
 	&Post{},
 	&Like{},
+	&Mention{},
+	&Bookmark{},
+	&Pin{},
+	&Poll{},
+	&PollOption{},
+	&PollVote{},
+	&PollChoice{},
+	&Conversation{},
+	&ConversationMember{},
+	&Message{},
+	&MessageDeletion{},
+	&List{},
+	&ListMember{},
+	&OutboundEmail{},
+	&Job{},
 }
 
 // BaseModel defines the basic structure for database models.
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// NotificationType represents the event a notification is about.
+type NotificationType string
+
+const (
+	NotificationLike          NotificationType = "like"           // A post was liked.
+	NotificationReply         NotificationType = "reply"          // A post was replied to.
+	NotificationFollow        NotificationType = "follow"         // The recipient was followed.
+	NotificationFollowRequest NotificationType = "follow_request" // The recipient was requested to be followed.
+)
+
+// Notification represents a group of events of the same type on the same target, such as every like on a post.
+// While a group is unread new events are added to it, the partial unique index makes sure there is only one unread group
+// for each target even when events happen concurrently. Once read, the next event starts a new group.
+type Notification struct {
+	BaseModel
+
+	RecipientID string `gorm:"not null;index;uniqueIndex:idx_notifications_unread_group,where:read_at IS NULL" json:"-"`
+	Recipient   *User  `gorm:"foreignKey:RecipientID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	Type     NotificationType `gorm:"size:32;not null;uniqueIndex:idx_notifications_unread_group" json:"type"`
+	GroupKey string           `gorm:"size:128;not null;uniqueIndex:idx_notifications_unread_group" json:"-"` // Identifies the target the events are grouped on.
+
+	// Post the events happened on, if any.
+	PostID *string `json:"post_id,omitempty"`
+	Post   *Post   `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"post,omitempty"`
+
+	// The most recent user to cause an event, and how many distinct users have caused one.
+	LatestActorID string `gorm:"not null" json:"-"`
+	LatestActor   *User  `gorm:"foreignKey:LatestActorID;references:ID;constraint:OnDelete:CASCADE" json:"latest_actor,omitempty"`
+	ActorCount    int64  `gorm:"not null;default:0" json:"actor_count"`
+
+	Actors []NotificationActor `gorm:"foreignKey:NotificationID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	LatestAt time.Time  `gorm:"not null;index" json:"latest_at"` // Time of the most recent event, used for ordering and read cursors.
+	ReadAt   *time.Time `json:"read_at"`
+}
+
+// NotificationActor represents a user who caused an event in a notification group, each user is only counted once.
+type NotificationActor struct {
+	BaseModel
+
+	NotificationID string `gorm:"not null;uniqueIndex:idx_notification_actors_notification_actor" json:"-"`
+	ActorID        string `gorm:"not null;uniqueIndex:idx_notification_actors_notification_actor" json:"actor_id"`
+	Actor          *User  `gorm:"foreignKey:ActorID;references:ID;constraint:OnDelete:CASCADE" json:"actor,omitempty"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Poll represents a poll attached to a post.
+type Poll struct {
+	BaseModel
+
+	// Post the poll is attached to, a post can only have a single poll.
+	PostID string `gorm:"not null;uniqueIndex" json:"-"`
+	Post   *Post  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	Multiple  bool      `gorm:"not null;default:false" json:"multiple"` // Whether voters can choose more than one option.
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`             // Votes are no longer accepted after this time.
+
+	// Duration is how long the poll accepts votes for once its post is published, see OpenPoll.
+	Duration time.Duration `gorm:"not null;default:0" json:"-"`
+
+	Options []PollOption `gorm:"foreignKey:PollID;references:ID;constraint:OnDelete:CASCADE" json:"options"`
+	// Votes are never serialised directly, the results are only shown once the poll can be revealed to the viewer.
+	Votes []PollVote `gorm:"foreignKey:PollID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Closed returns whether the poll has stopped accepting votes.
+func (p *Poll) Closed() bool {
+	return time.Now().After(p.ExpiresAt)
+}
+
+// OpenPoll opens the poll of a post, if it has one, now that the post was published at the time.
+// Drafts and scheduled posts can be published at any time, so their poll closes its duration after publishing.
+func OpenPoll(tx *gorm.DB, postID string, at time.Time) error {
+	var poll Poll
+	if err := tx.Where(Poll{PostID: postID}).Limit(1).Find(&poll).Error; err != nil {
+		return err
+	}
+
+	// Polls without a duration close at the time they were given.
+	if poll.ID == "" || poll.Duration == 0 {
+		return nil
+	}
+
+	return tx.Model(&poll).Update("expires_at", at.Add(poll.Duration)).Error
+}
+
+// PollOption represents a single option that can be voted for in a poll.
+type PollOption struct {
+	BaseModel
+
+	// The position is unique within the poll so options are always returned in the order they were created.
+	PollID   string `gorm:"not null;uniqueIndex:idx_poll_options_poll_position" json:"-"`
+	Position int    `gorm:"not null;uniqueIndex:idx_poll_options_poll_position" json:"position"`
+
+	Text string `gorm:"size:64;not null" json:"text"`
+}
+
+// PollVote represents a user's ballot in a poll.
+// The unique index on the poll and user stops a user voting twice, even when requests are made concurrently.
+type PollVote struct {
+	BaseModel
+
+	PollID string `gorm:"not null;uniqueIndex:idx_poll_votes_poll_user" json:"-"`
+	UserID string `gorm:"not null;uniqueIndex:idx_poll_votes_poll_user" json:"-"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Choices holds every option chosen on the ballot, only multiple choice polls can have more than one.
+	Choices []PollChoice `gorm:"foreignKey:VoteID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PollChoice represents an option chosen on a ballot.
+type PollChoice struct {
+	BaseModel
+
+	VoteID   string      `gorm:"not null;uniqueIndex:idx_poll_choices_vote_option" json:"-"`
+	OptionID string      `gorm:"not null;uniqueIndex:idx_poll_choices_vote_option" json:"option_id"`
+	Option   *PollOption `gorm:"foreignKey:OptionID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -1,5 +1,19 @@
 package models
 
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// PostStatus represents the publishing state of a post.
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"     // Saved by the author but not published.
+	PostStatusScheduled PostStatus = "scheduled" // Published automatically once PublishAt has passed.
+	PostStatusPublished PostStatus = "published" // Visible to everyone.
+)
+
 // Post represents a post made by a user.
 type Post struct {
 	BaseModel
@@ -10,8 +24,22 @@ type Post struct {
 
 	Content string `gorm:"size:512" json:"content"`
 
+	// Publishing, drafts and scheduled posts are only visible to their author until they are published.
+	Status    PostStatus `gorm:"size:16;not null;default:published;index" json:"status"`
+	PublishAt *time.Time `gorm:"index" json:"publish_at,omitempty"` // When a scheduled post will be published.
+
+	// Visibility decides who can see the post, it is enforced by the VisibleTo scope.
+	Visibility Visibility `gorm:"size:16;not null;default:public" json:"visibility"`
+	Mentions   []Mention  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"mentions,omitempty"`
+
 	// Relations
 	Likes []Like `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"likes,omitempty"`
+	// Poll attached to the post, results are exposed through the extended post instead.
+	Poll *Poll `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	// Bookmarks are private to the user who made them, so they are never serialised.
+	Bookmarks []Bookmark `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	// Pins are exposed through the author's profile instead.
+	Pins []Pin `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
 
 	// -- Replies
 	// Parent is only used when a post is a reply to another post.
@@ -31,3 +59,47 @@ type Like struct {
 	PostID string `gorm:"not null" json:"post_id"`
 	Post   *Post  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"post,omitempty"`
 }
+
+// Bookmark represents a post privately saved by a user.
+type Bookmark struct {
+	BaseModel
+
+	// User that saved the post, a user can only bookmark a post once.
+	UserID string `gorm:"not null;uniqueIndex:idx_bookmarks_user_post" json:"-"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	PostID string `gorm:"not null;uniqueIndex:idx_bookmarks_user_post" json:"post_id"`
+	Post   *Post  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"post,omitempty"`
+}
+
+// Pin represents a post pinned to its author's profile, pinned posts are shown in the order they were pinned.
+type Pin struct {
+	BaseModel
+
+	UserID string `gorm:"not null;uniqueIndex:idx_pins_user_post" json:"-"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// The pin is deleted along with the post.
+	PostID string `gorm:"not null;uniqueIndex:idx_pins_user_post" json:"post_id"`
+	Post   *Post  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"post,omitempty"`
+}
+
+// PinnedBy is a GORM scope that only returns the posts pinned by the user, in the order they were pinned.
+func PinnedBy(userID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.
+			Joins("JOIN pins ON pins.post_id = posts.id AND pins.user_id = ?", userID).
+			Order("pins.created_at asc")
+	}
+}
+
+// Mention represents a user mentioned in a post.
+type Mention struct {
+	BaseModel
+
+	PostID string `gorm:"not null;uniqueIndex:idx_mentions_post_user" json:"-"`
+	Post   *Post  `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID string `gorm:"not null;uniqueIndex:idx_mentions_post_user;index" json:"user_id"`
+	User   *User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -11,6 +11,12 @@ type User struct {
 	Username    string `gorm:"size:64;not null;unique" json:"username"`
 	DisplayName string `gorm:"size:512" json:"display_name"`
 
+	// Protected accounts approve their followers, and their posts are only visible to those followers.
+	Protected bool `gorm:"not null;default:false" json:"protected"`
+
+	// Only accept direct messages from users who follow the account.
+	DMFollowersOnly bool `gorm:"not null;default:false" json:"dm_followers_only"`
+
 	Email string `gorm:"size:255;unique;not null" json:"email,omitempty"` // Ommitted for security reasons
 
 	Connections []Connection `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"connections,omitempty"`
@@ -18,6 +24,8 @@ type User struct {
 	Posts []Post `gorm:"foreignKey:AuthorID;references:ID;constraint:OnDelete:CASCADE" json:"posts,omitempty"`
 	Likes []Like `gorm:"foreignKey:LikedByID;references:ID;constraint:OnDelete:CASCADE" json:"likes,omitempty"`
 
+	Bookmarks []Bookmark `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"` // Private to the user
+
 	Followers []Follow `gorm:"foreignKey:FollowingID;references:ID;constraint:OnDelete:CASCADE" json:"followers,omitempty"`
 	Following []Follow `gorm:"foreignKey:FollowerID;references:ID;constraint:OnDelete:CASCADE" json:"following,omitempty"`
 }
@@ -27,13 +35,48 @@ type Follow struct {
 	BaseModel
 
 	// Follower and Following are the users involved in the follow relationship.
-	FollowerID string `gorm:"not null" json:"follower_id"`
+	FollowerID string `gorm:"not null;uniqueIndex:idx_follows_follower_following" json:"follower_id"`
 	Follower   *User  `gorm:"foreignKey:FollowerID;references:ID;constraint:OnDelete:CASCADE" json:"follower,omitempty"`
 
-	FollowingID string `gorm:"not null" json:"following_id"`
+	FollowingID string `gorm:"not null;uniqueIndex:idx_follows_follower_following;index" json:"following_id"`
 	Following   *User  `gorm:"foreignKey:FollowingID;references:ID;constraint:OnDelete:CASCADE" json:"following,omitempty"`
 }
 
+// FollowRequest represents a pending request to follow a protected account.
+// The request is deleted once it is approved, which creates the Follow, or rejected.
+type FollowRequest struct {
+	BaseModel
+
+	RequesterID string `gorm:"not null;uniqueIndex:idx_follow_requests_requester_target" json:"requester_id"`
+	Requester   *User  `gorm:"foreignKey:RequesterID;references:ID;constraint:OnDelete:CASCADE" json:"requester,omitempty"`
+
+	TargetID string `gorm:"not null;uniqueIndex:idx_follow_requests_requester_target;index" json:"target_id"`
+	Target   *User  `gorm:"foreignKey:TargetID;references:ID;constraint:OnDelete:CASCADE" json:"target,omitempty"`
+}
+
+// Block represents a user blocking another user.
+// Blocked users cannot follow, reply to, like or mention the blocker, and neither can see the other's posts.
+type Block struct {
+	BaseModel
+
+	BlockerID string `gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked" json:"-"`
+	Blocker   *User  `gorm:"foreignKey:BlockerID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	BlockedID string `gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked;index" json:"blocked_id"`
+	Blocked   *User  `gorm:"foreignKey:BlockedID;references:ID;constraint:OnDelete:CASCADE" json:"blocked,omitempty"`
+}
+
+// Mute represents a user muting another user, muted users are filtered out of the muter's timelines and notifications.
+type Mute struct {
+	BaseModel
+
+	MuterID string `gorm:"not null;uniqueIndex:idx_mutes_muter_muted" json:"-"`
+	Muter   *User  `gorm:"foreignKey:MuterID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+
+	MutedID string `gorm:"not null;uniqueIndex:idx_mutes_muter_muted" json:"muted_id"`
+	Muted   *User  `gorm:"foreignKey:MutedID;references:ID;constraint:OnDelete:CASCADE" json:"muted,omitempty"`
+}
+
 // ConnectionType represents the type of connection.
 type ConnectionType string
 
@@ -81,4 +124,8 @@ type Session struct {
 	Connection   *Connection `gorm:"foreignKey:ConnectionID;references:ID;constraint:OnDelete:CASCADE" json:"connection,omitempty"`
 
 	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+
+	// PrimaryUntil pins the reads of the session to the primary database for a short window after its user wrote,
+	// every session of the user is pinned so they see their own writes whichever client they read from next.
+	PrimaryUntil *time.Time `json:"-"`
 }
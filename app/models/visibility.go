@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Visibility represents who is able to see a post.
+type Visibility string
+
+const (
+	VisibilityPublic    Visibility = "public"    // Visible to everyone.
+	VisibilityFollowers Visibility = "followers" // Visible to the author's followers and anyone mentioned.
+	VisibilityMentioned Visibility = "mentioned" // Visible only to the users mentioned in the post.
+)
+
+// visibilityLevels orders the visibility levels from the least to the most restrictive.
+var visibilityLevels = map[Visibility]int{
+	VisibilityPublic:    0,
+	VisibilityFollowers: 1,
+	VisibilityMentioned: 2,
+}
+
+// Restrict returns the most restrictive of the two visibility levels.
+func (v Visibility) Restrict(other Visibility) Visibility {
+	if visibilityLevels[other] > visibilityLevels[v] {
+		return other
+	}
+	return v
+}
+
+// accessibleSQL builds the condition that decides whether the viewer can see the content of the user in the given column.
+// Content is never accessible when either user has blocked the other, and content from protected accounts is
+// only accessible to the account itself and its approved followers.
+func accessibleSQL(column, viewerID string) (string, []interface{}) {
+	return fmt.Sprintf(`(
+		NOT EXISTS (SELECT 1 FROM blocks WHERE (blocks.blocker_id = ? AND blocks.blocked_id = %[1]s) OR (blocks.blocker_id = %[1]s AND blocks.blocked_id = ?))
+		AND (
+			%[1]s = ?
+			OR NOT EXISTS (SELECT 1 FROM users WHERE users.id = %[1]s AND users.protected = ?)
+			OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = ? AND follows.following_id = %[1]s)
+		)
+	)`, column), []interface{}{viewerID, viewerID, viewerID, true, viewerID}
+}
+
+// visibleSQL builds the condition that decides whether the post in the given table alias can be seen by the viewer.
+// An empty viewer ID is an anonymous viewer, which only matches public posts from accounts that are not protected.
+func visibleSQL(alias, viewerID string) (string, []interface{}) {
+	accessible, args := accessibleSQL(alias+".author_id", viewerID)
+
+	return fmt.Sprintf(`%[1]s.status = '%[2]s' AND %[6]s AND (
+		%[1]s.visibility = '%[3]s'
+		OR %[1]s.author_id = ?
+		OR (%[1]s.visibility = '%[4]s' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = ? AND follows.following_id = %[1]s.author_id))
+		OR (%[1]s.visibility IN ('%[4]s', '%[5]s') AND EXISTS (SELECT 1 FROM mentions WHERE mentions.post_id = %[1]s.id AND mentions.user_id = ?))
+	)`, alias, PostStatusPublished, VisibilityPublic, VisibilityFollowers, VisibilityMentioned, accessible),
+		append(args, viewerID, viewerID, viewerID)
+}
+
+// VisibleTo is a GORM scope that only returns posts the viewer is allowed to see.
+// Every read of posts goes through this scope so unpublished and restricted posts can never leak,
+// replies are additionally hidden whenever their parent is hidden from the viewer.
+func VisibleTo(viewerID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		postSQL, args := visibleSQL("posts", viewerID)
+		parentSQL, parentArgs := visibleSQL("parents", viewerID)
+
+		return tx.Where(
+			"("+postSQL+") AND (posts.parent_id IS NULL OR EXISTS (SELECT 1 FROM posts parents WHERE parents.id = posts.parent_id AND "+parentSQL+"))",
+			append(args, parentArgs...)...,
+		)
+	}
+}
+
+// LikesVisibleTo is a GORM scope that hides the likes made by protected accounts from anyone who is not an approved follower,
+// and the likes made by blocked users.
+func LikesVisibleTo(viewerID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		accessible, args := accessibleSQL("likes.liked_by_id", viewerID)
+		return tx.Where(accessible, args...)
+	}
+}
+
+// NotMutedBy is a GORM scope that filters the posts of users muted by the viewer out of timelines.
+// Unlike VisibleTo it is only applied to listings, muted users' posts can still be opened directly.
+func NotMutedBy(viewerID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.muter_id = ? AND mutes.muted_id = posts.author_id)", viewerID)
+	}
+}
+
+// BlockBetween is a GORM scope on blocks that matches a block in either direction between the two users.
+func BlockBetween(userID, otherID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("(blocks.blocker_id = ? AND blocks.blocked_id = ?) OR (blocks.blocker_id = ? AND blocks.blocked_id = ?)",
+			userID, otherID, otherID, userID)
+	}
+}
@@ -0,0 +1,55 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Event is a single message published to a topic.
+// IDs increase monotonically across every topic, so a subscriber can resume from the last ID it received.
+type Event struct {
+	ID    uint64          `json:"id"`
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Broker delivers published events to the subscribers of their topic.
+// The in-process Hub is used by default, a broker backed by an external service can replace it to scale out.
+type Broker interface {
+	// Publish sends the data, encoded as JSON, to every subscriber of the topic.
+	Publish(topic, eventType string, data any) error
+	// Signal sends the data, encoded as JSON, to every current subscriber of the topic without retaining it.
+	// Signals are ephemeral, such as presence, so they have no event ID and are never replayed.
+	Signal(topic, eventType string, data any) error
+	// Subscribe listens to the topics, replaying any retained events published after lastEventID.
+	Subscribe(topics []string, lastEventID uint64) *Subscription
+	// Close ends every subscription and every one made after, so open streams finish when the server shuts down.
+	Close()
+}
+
+// Subscription is a live feed of events from one or more topics.
+type Subscription struct {
+	// Replay holds the events missed since the last event ID provided when subscribing, oldest first.
+	Replay []Event
+	// Events receives every event published after the subscription was created.
+	// The channel is closed when the subscription is closed, including when the broker drops a subscriber that fell behind.
+	Events <-chan Event
+
+	once  sync.Once
+	close func()
+}
+
+// NewSubscription creates a subscription for a broker, close is called once when the subscription is closed.
+func NewSubscription(replay []Event, events <-chan Event, close func()) *Subscription {
+	return &Subscription{
+		Replay: replay,
+		Events: events,
+		close:  close,
+	}
+}
+
+// Close stops the subscription, it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(s.close)
+}
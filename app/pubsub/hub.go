@@ -0,0 +1,176 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+const (
+	historySize = 256 // Events retained per topic for resuming subscribers.
+	bufferSize  = 64  // Events buffered per subscriber before it is considered too slow and dropped.
+)
+
+// Hub is an in-process broker, it only reaches subscribers connected to the same instance.
+type Hub struct {
+	mu          sync.Mutex
+	seq         uint64
+	history     map[string][]Event
+	subscribers map[string]map[*hubSubscriber]struct{}
+	closed      bool
+}
+
+// hubSubscriber is the hub's side of a subscription.
+type hubSubscriber struct {
+	topics []string
+	events chan Event
+	closed bool
+}
+
+// NewHub creates an empty in-process hub.
+func NewHub() *Hub {
+	return &Hub{
+		history:     make(map[string][]Event),
+		subscribers: make(map[string]map[*hubSubscriber]struct{}),
+	}
+}
+
+// Publish sends the event to every subscriber of the topic and retains it for resuming subscribers.
+func (h *Hub) Publish(topic, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event := Event{
+		ID:    h.seq,
+		Topic: topic,
+		Type:  eventType,
+		Data:  payload,
+	}
+
+	// Retain the event, dropping the oldest once the history is full.
+	history := append(h.history[topic], event)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	h.history[topic] = history
+
+	h.deliver(event)
+
+	return nil
+}
+
+// Signal sends the event to every current subscriber of the topic without retaining it.
+func (h *Hub) Signal(topic, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deliver(Event{
+		Topic: topic,
+		Type:  eventType,
+		Data:  payload,
+	})
+
+	return nil
+}
+
+// deliver sends the event to every subscriber of its topic, the lock must be held.
+func (h *Hub) deliver(event Event) {
+	for subscriber := range h.subscribers[event.Topic] {
+		select {
+		case subscriber.events <- event:
+		default:
+			// The subscriber is not keeping up, drop it rather than blocking every publisher.
+			// It can reconnect and resume from the last event it received.
+			h.remove(subscriber)
+		}
+	}
+}
+
+// Subscribe listens to the topics, replaying the retained events published after lastEventID.
+func (h *Hub) Subscribe(topics []string, lastEventID uint64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriber := &hubSubscriber{
+		topics: topics,
+		events: make(chan Event, bufferSize),
+	}
+
+	// The hub is closed, so the subscription ends straight away.
+	if h.closed {
+		subscriber.closed = true
+		close(subscriber.events)
+		return NewSubscription(nil, subscriber.events, func() {})
+	}
+
+	// Collect the missed events while holding the lock, so nothing is missed or sent twice.
+	var replay []Event
+	if lastEventID > 0 {
+		for _, topic := range topics {
+			for _, event := range h.history[topic] {
+				if event.ID > lastEventID {
+					replay = append(replay, event)
+				}
+			}
+		}
+
+		sort.Slice(replay, func(i, j int) bool {
+			return replay[i].ID < replay[j].ID
+		})
+	}
+
+	for _, topic := range topics {
+		if h.subscribers[topic] == nil {
+			h.subscribers[topic] = make(map[*hubSubscriber]struct{})
+		}
+		h.subscribers[topic][subscriber] = struct{}{}
+	}
+
+	return NewSubscription(replay, subscriber.events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.remove(subscriber)
+	})
+}
+
+// Close ends every subscription, closing their channels, and ends every subscription made after straight away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subscribers := range h.subscribers {
+		for subscriber := range subscribers {
+			h.remove(subscriber)
+		}
+	}
+}
+
+// remove unregisters the subscriber from all its topics and closes its channel, the lock must be held.
+func (h *Hub) remove(subscriber *hubSubscriber) {
+	if subscriber.closed {
+		return
+	}
+	subscriber.closed = true
+
+	for _, topic := range subscriber.topics {
+		delete(h.subscribers[topic], subscriber)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+
+	close(subscriber.events)
+}
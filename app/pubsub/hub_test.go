@@ -0,0 +1,46 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+// closed waits for the subscription's channel to be closed, failing the test if it takes too long.
+func closed(t *testing.T, subscription *Subscription) {
+	t.Helper()
+
+	for {
+		select {
+		case _, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the subscription to be closed")
+		}
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := NewHub()
+
+	first := h.Subscribe([]string{PublicTopic}, 0)
+	second := h.Subscribe([]string{PublicTopic, UserTopic("alice")}, 0)
+	if err := h.Publish(PublicTopic, "post", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Close()
+	closed(t, first)
+	closed(t, second)
+
+	// Subscriptions made while shutting down end straight away, and closing them again is harmless.
+	late := h.Subscribe([]string{PublicTopic}, 0)
+	closed(t, late)
+	late.Close()
+	first.Close()
+
+	if err := h.Publish(PublicTopic, "post", "hello"); err != nil {
+		t.Fatal(err)
+	}
+}
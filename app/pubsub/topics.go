@@ -0,0 +1,171 @@
+package pubsub
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+	"log/slog"
+)
+
+// PublicTopic carries the global feed, new public top-level posts.
+const PublicTopic = "public"
+
+// UserTopic returns the topic carrying a user's notifications and home timeline inserts.
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
+
+// ThreadTopic returns the topic carrying the live updates of a post and its direct replies.
+// Thread events are only delivered live, so they are signalled rather than retained.
+func ThreadTopic(postID string) string {
+	return "thread:" + postID
+}
+
+// Event types published by the application.
+const (
+	EventPost         = "post"         // A post was published to the global feed.
+	EventTimeline     = "timeline"     // A post was inserted into the user's home timeline.
+	EventNotification = "notification" // The user received a notification.
+	EventReply        = "reply"        // A reply was published in a thread.
+	EventLikes        = "likes"        // The like count of a post in a thread changed.
+	EventPresence     = "presence"     // A user started replying in a thread.
+	EventMessage      = "message"      // The user received a direct message.
+)
+
+// PostEvent is the data of post and timeline events.
+// Only identifiers are sent, clients fetch the post itself so every visibility rule is still applied.
+type PostEvent struct {
+	PostID   string `json:"post_id"`
+	AuthorID string `json:"author_id"`
+}
+
+// LikesEvent is the data of likes events.
+type LikesEvent struct {
+	PostID string `json:"post_id"`
+	Likes  int64  `json:"likes"`
+}
+
+// PresenceEvent is the data of presence events.
+type PresenceEvent struct {
+	PostID string `json:"post_id"`
+	UserID string `json:"user_id"`
+	State  string `json:"state"`
+}
+
+// MessageEvent is the data of message events.
+type MessageEvent struct {
+	ConversationID string `json:"conversation_id"`
+	MessageID      string `json:"message_id"`
+	SenderID       string `json:"sender_id"`
+}
+
+// NotificationEvent is the data of notification events.
+type NotificationEvent struct {
+	NotificationID string                  `json:"notification_id"`
+	Type           models.NotificationType `json:"type"`
+	PostID         *string                 `json:"post_id,omitempty"`
+}
+
+// Publisher announces the application's events to the subscribers of every topic they belong to.
+type Publisher struct {
+	db     *gorm.DB
+	broker Broker
+}
+
+// NewPublisher creates a publisher that looks up the recipients of events in the database.
+func NewPublisher(db *gorm.DB, broker Broker) *Publisher {
+	return &Publisher{
+		db:     db,
+		broker: broker,
+	}
+}
+
+// PublishPost announces a newly published top-level post on the global feed and the home timelines of the author's followers.
+// Events are a side effect of publishing, so failures are logged rather than returned.
+func (p *Publisher) PublishPost(post models.Post) {
+	if post.Status != models.PostStatusPublished || post.ParentID != nil {
+		return
+	}
+
+	data := PostEvent{
+		PostID:   post.ID,
+		AuthorID: post.AuthorID,
+	}
+
+	// Get the author to check if their posts are public.
+	var author models.User
+	if err := p.db.
+		Select("id", "protected").
+		Where("id = ?", post.AuthorID).
+		First(&author).Error; err != nil {
+		slog.With("post", post.ID, "error", err).Error("failed to find the author of a published post")
+		return
+	}
+
+	// Only public posts from accounts that are not protected reach the global feed.
+	if post.Visibility == models.VisibilityPublic && !author.Protected {
+		if err := p.broker.Publish(PublicTopic, EventPost, data); err != nil {
+			slog.With("post", post.ID, "error", err).Error("failed to publish post event")
+		}
+	}
+
+	// Posts only visible to the users mentioned never reach the home timelines of followers.
+	recipients := []string{post.AuthorID}
+	if post.Visibility != models.VisibilityMentioned {
+		var followers []string
+		if err := p.db.Model(&models.Follow{}).
+			Where(models.Follow{FollowingID: post.AuthorID}).
+			// Muted users are filtered out of the muter's timelines.
+			Where("NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.muter_id = follows.follower_id AND mutes.muted_id = follows.following_id)").
+			Pluck("follower_id", &followers).Error; err != nil {
+			slog.With("post", post.ID, "error", err).Error("failed to find the followers of a post's author")
+			return
+		}
+
+		recipients = append(recipients, followers...)
+	}
+
+	for _, recipient := range recipients {
+		if err := p.broker.Publish(UserTopic(recipient), EventTimeline, data); err != nil {
+			slog.With("post", post.ID, "recipient", recipient, "error", err).Error("failed to publish timeline event")
+		}
+	}
+}
+
+// PublishReply announces a newly published reply to the subscribers of the thread it replies to.
+func (p *Publisher) PublishReply(reply models.Post) {
+	if reply.Status != models.PostStatusPublished || reply.ParentID == nil {
+		return
+	}
+
+	if err := p.broker.Signal(ThreadTopic(*reply.ParentID), EventReply, PostEvent{
+		PostID:   reply.ID,
+		AuthorID: reply.AuthorID,
+	}); err != nil {
+		slog.With("post", reply.ID, "error", err).Error("failed to publish reply event")
+	}
+}
+
+// PublishLikes announces the current like count of a post to the subscribers of its own thread and the thread it replies to.
+func (p *Publisher) PublishLikes(post models.Post) {
+	var likes int64
+	if err := p.db.Model(&models.Like{}).Where(models.Like{PostID: post.ID}).Count(&likes).Error; err != nil {
+		slog.With("post", post.ID, "error", err).Error("failed to count the likes of a post")
+		return
+	}
+
+	data := LikesEvent{
+		PostID: post.ID,
+		Likes:  likes,
+	}
+
+	topics := []string{ThreadTopic(post.ID)}
+	if post.ParentID != nil {
+		topics = append(topics, ThreadTopic(*post.ParentID))
+	}
+
+	for _, topic := range topics {
+		if err := p.broker.Signal(topic, EventLikes, data); err != nil {
+			slog.With("post", post.ID, "error", err).Error("failed to publish likes event")
+		}
+	}
+}
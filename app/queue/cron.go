@@ -0,0 +1,161 @@
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression, the times it matches are in UTC.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// When both the day of the month and the day of the week are restricted, a day matching either runs the job,
+	// as it does in cron.
+	domRestricted, dowRestricted bool
+}
+
+// descriptors are the shorthands accepted in place of the five fields.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is the range of values of a cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // Sunday is both 0 and 7.
+}
+
+// ParseSchedule parses a cron expression of five fields, minute, hour, day of month, month and day of week,
+// such as "*/15 * * * *" or "0 3 * * 1-5". Fields can be *, a value, a range such as 1-5, a list such as 1,15
+// and have a step such as */10 or 0-30/5. The descriptors @hourly, @daily, @weekly, @monthly and @yearly are accepted too.
+func ParseSchedule(expression string) (Schedule, error) {
+	if descriptor, ok := descriptors[expression]; ok {
+		expression = descriptor
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("cron %q: must have %d fields", expression, len(fields))
+	}
+
+	var values [5]uint64
+	for i, part := range parts {
+		bits, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("cron %q: %w", expression, err)
+		}
+		values[i] = bits
+	}
+
+	// Fold Sunday as 7 onto 0.
+	if values[4]&(1<<7) != 0 {
+		values[4] = values[4]&^(1<<7) | 1
+	}
+
+	return Schedule{
+		minute:        values[0],
+		hour:          values[1],
+		dom:           values[2],
+		month:         values[3],
+		dow:           values[4],
+		domRestricted: parts[2] != "*",
+		dowRestricted: parts[4] != "*",
+	}, nil
+}
+
+// parseField parses a comma separated list of the values of a field into a bit set.
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rng, step, hasStep := strings.Cut(item, "/")
+
+		every := 1
+		if hasStep {
+			var err error
+			if every, err = strconv.Atoi(step); err != nil || every < 1 {
+				return 0, fmt.Errorf("%s: step %q must be a positive integer", f.name, step)
+			}
+		}
+
+		start, end := f.min, f.max
+		if rng != "*" {
+			low, high, isRange := strings.Cut(rng, "-")
+
+			var err error
+			if start, err = strconv.Atoi(low); err != nil {
+				return 0, fmt.Errorf("%s: %q is not a number", f.name, low)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(high); err != nil {
+					return 0, fmt.Errorf("%s: %q is not a number", f.name, high)
+				}
+			} else if hasStep {
+				// A single value with a step, such as 5/15, runs from the value to the end of the range.
+				end = f.max
+			}
+		}
+
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("%s: %q must be within %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for value := start; value <= end; value += every {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// dayMatches checks whether the schedule runs on the day.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// Next returns the first time the schedule matches after the time, or the zero time if it never does,
+// such as the 31st of February.
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// Every schedule that can match does so within a few years, such as the 29th of February.
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
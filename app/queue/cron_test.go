@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday.
+	after := time.Date(2026, time.January, 14, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		next       time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.January, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.January, 14, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, time.January, 14, 10, 25, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, time.January, 15, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2026, time.January, 14, 13, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * 3 *", time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.January, 14, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Either the day of the month or the day of the week, as both are restricted.
+		{"0 0 20 * 5", time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if next := schedule.Next(after); !next.Equal(test.next) {
+				t.Errorf("expected %s, got %s", test.next, next)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := ParseSchedule(expression); err == nil {
+			t.Errorf("expected %q to be invalid", expression)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// defaultMaxAttempts is how many times a job is attempted unless it is enqueued with MaxAttempts.
+const defaultMaxAttempts = 5
+
+// Kind is a type of job along with the type of its payload, so enqueueing a job and handling it agree on the payload.
+// Kinds are declared once as package variables and shared by the code enqueueing them and their handler.
+type Kind[T any] struct {
+	Name string
+}
+
+// NewKind declares a kind of job, the name must be unique and should not change while jobs of the kind are queued.
+func NewKind[T any](name string) Kind[T] {
+	return Kind[T]{Name: name}
+}
+
+// EnqueueOption changes a job before it is enqueued.
+type EnqueueOption func(job *models.Job)
+
+// RunAt holds the job back until the time, by default it runs straight away.
+func RunAt(t time.Time) EnqueueOption {
+	return func(job *models.Job) {
+		job.RunAt = t
+	}
+}
+
+// RunIn holds the job back for the duration.
+func RunIn(d time.Duration) EnqueueOption {
+	return RunAt(time.Now().Add(d))
+}
+
+// UniqueKey only enqueues the job if there is no pending job with the same key, such as "reconcile_counts:<post>",
+// so work requested many times before it runs is only done once.
+func UniqueKey(key string) EnqueueOption {
+	return func(job *models.Job) {
+		job.UniqueKey = &key
+	}
+}
+
+// MaxAttempts is how many times the job is attempted before it is dead, by default it is 5.
+func MaxAttempts(attempts int) EnqueueOption {
+	return func(job *models.Job) {
+		job.MaxAttempts = max(attempts, 1)
+	}
+}
+
+// Enqueue adds a job of the kind to the queue, use the transaction of the change that needs the job done,
+// so the job is only enqueued if the change commits. Jobs with a unique key that is already pending are dropped.
+func (k Kind[T]) Enqueue(tx *gorm.DB, payload T, options ...EnqueueOption) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	job := models.Job{
+		Kind:        k.Name,
+		Payload:     string(encoded),
+		Status:      models.JobPending,
+		RunAt:       time.Now(),
+		MaxAttempts: defaultMaxAttempts,
+	}
+	for _, option := range options {
+		option(&job)
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error
+}
+
+// HandlerOption changes how the jobs of a kind are handled.
+type HandlerOption func(h *handler)
+
+// Timeout is how long a job of the kind can run before its context is cancelled, by default it is 5 minutes.
+// A worker that stops without finishing a job leaves it claimed for the longest timeout of any kind,
+// before another worker retries it.
+func Timeout(d time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.timeout = d
+	}
+}
+
+// Handle registers the handler of a kind of job with the queue, it must be called before the queue is started.
+// Every instance must handle every kind, otherwise jobs of that kind are only run by the instances that do.
+//
+// The handler should return once the context is cancelled, which happens when the job times out or the server
+// shuts down. Returning an error retries the job with exponential backoff until it has run out of attempts.
+func Handle[T any](q *Queue, kind Kind[T], fn func(ctx context.Context, payload T) error, options ...HandlerOption) {
+	h := &handler{
+		timeout: defaultTimeout,
+		run: func(ctx context.Context, payload string) error {
+			var decoded T
+			if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+				return err
+			}
+
+			return fn(ctx, decoded)
+		},
+	}
+	for _, option := range options {
+		option(h)
+	}
+
+	q.register(kind.Name, h)
+}
+
+// Recurring enqueues a job of the kind with the payload on a cron schedule, see ParseSchedule, the kind must be handled.
+// Each run is only enqueued once however many instances there are. A failed run is not retried or kept as dead,
+// the next run does the work instead.
+func Recurring[T any](q *Queue, kind Kind[T], schedule string, payload T) {
+	parsed, err := ParseSchedule(schedule)
+	if err != nil {
+		panic("queue: recurring job " + kind.Name + ": " + err.Error())
+	}
+
+	addRecurring(q, kind, parsed.Next, payload)
+}
+
+// Every enqueues a job of the kind with the payload at a fixed interval, for work due more often than a cron schedule
+// allows, such as sending emails. The runs are aligned to the interval, so every instance agrees on the next one,
+// and otherwise behave like Recurring.
+func Every[T any](q *Queue, kind Kind[T], interval time.Duration, payload T) {
+	if interval <= 0 {
+		panic("queue: recurring job " + kind.Name + ": the interval must be positive")
+	}
+
+	addRecurring(q, kind, func(after time.Time) time.Time {
+		return after.Truncate(interval).Add(interval)
+	}, payload)
+}
+
+// recurringKey is the unique key of the runs of a recurring job, so only its next run is ever pending.
+func recurringKey(kind string) string {
+	return "recurring:" + kind
+}
+
+// addRecurring adds a recurring job of the kind, next returns the time of the run after the given time.
+func addRecurring[T any](q *Queue, kind Kind[T], next func(after time.Time) time.Time, payload T) {
+	q.recurring = append(q.recurring, recurring{
+		kind: kind.Name,
+		next: next,
+		enqueue: func(tx *gorm.DB, at time.Time) error {
+			return kind.Enqueue(tx, payload, RunAt(at), UniqueKey(recurringKey(kind.Name)), MaxAttempts(1))
+		},
+	})
+}
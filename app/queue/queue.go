@@ -0,0 +1,343 @@
+// Package queue is a job queue backed by the database, for work that should happen in the background and survive
+// restarts, such as data exports, purges and fan-out. Jobs are claimed with FOR UPDATE SKIP LOCKED, so any number
+// of instances can run them, and each job only runs on one instance at a time.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/cfg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTimeout is how long a job can run unless its kind is handled with Timeout.
+	defaultTimeout = 5 * time.Minute
+
+	// The delay before retrying a failed job doubles after each attempt, up to maxRetryDelay.
+	retryDelay    = 10 * time.Second
+	maxRetryDelay = time.Hour
+
+	// recurringInterval is how often each instance makes sure the next run of every recurring job is enqueued.
+	recurringInterval = time.Minute
+
+	// releaseTimeout is how long cancelled jobs are given to be released when draining runs out of time.
+	releaseTimeout = 5 * time.Second
+)
+
+// handler runs the jobs of a kind.
+type handler struct {
+	timeout time.Duration
+	run     func(ctx context.Context, payload string) error
+}
+
+// recurring is a job enqueued on a schedule, next is the time of the run after the given time.
+type recurring struct {
+	kind    string
+	next    func(after time.Time) time.Time
+	enqueue func(tx *gorm.DB, at time.Time) error
+}
+
+// Queue runs the jobs in the database with the handlers registered for their kinds.
+type Queue struct {
+	db     *gorm.DB
+	config *cfg.Configuration
+
+	handlers  map[string]*handler
+	recurring []recurring
+
+	// lease is how long a claimed job is hidden from other workers, the longest timeout of any kind.
+	lease   time.Duration
+	started bool
+
+	stopping chan struct{}  // Closed by Stop, so the workers stop claiming jobs.
+	wg       sync.WaitGroup // The workers and the recurring job scheduler.
+
+	// The context of the running jobs, cancelled once draining finishes or runs out of time.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a queue over the database, register the handlers of the jobs it runs before starting it.
+func New(db *gorm.DB, config *cfg.Configuration) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		db:       db,
+		config:   config,
+		handlers: make(map[string]*handler),
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// register adds the handler of a kind, registering the same kind twice is a mistake that panics like a duplicate route.
+func (q *Queue) register(kind string, h *handler) {
+	if q.started {
+		panic("queue: " + kind + " must be handled before the queue is started")
+	}
+	if _, ok := q.handlers[kind]; ok {
+		panic("queue: " + kind + " is already handled")
+	}
+
+	q.handlers[kind] = h
+}
+
+// Start runs JOB_WORKERS workers in the background, along with the scheduler of the recurring jobs.
+func (q *Queue) Start() {
+	for _, r := range q.recurring {
+		if _, ok := q.handlers[r.kind]; !ok {
+			panic("queue: recurring job " + r.kind + " is not handled")
+		}
+	}
+
+	q.started = true
+	q.lease = defaultTimeout
+	for _, h := range q.handlers {
+		q.lease = max(q.lease, h.timeout)
+	}
+
+	var kinds []string
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) > 0 {
+		for range q.config.JobWorkers {
+			q.wg.Add(1)
+			go q.work(kinds)
+		}
+	}
+
+	if len(q.recurring) > 0 {
+		q.wg.Add(1)
+		go q.schedule()
+	}
+
+	slog.With("workers", q.config.JobWorkers, "kinds", len(kinds), "recurring", len(q.recurring)).Info("started job queue")
+}
+
+// Stop stops claiming jobs and waits for the running jobs to finish, until the context ends.
+// Jobs still running then are cancelled and released to run again, and the context's error is returned.
+func (q *Queue) Stop(ctx context.Context) error {
+	close(q.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+
+		select {
+		case <-done:
+		case <-time.After(releaseTimeout):
+			slog.Warn("jobs did not stop once cancelled, they run again once their lease expires")
+		}
+		return ctx.Err()
+	}
+}
+
+// work claims and runs jobs one at a time until the queue stops, waiting for JOB_POLL_INTERVAL while there are none.
+func (q *Queue) work(kinds []string) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stopping:
+			return
+		default:
+		}
+
+		job, err := q.claim(kinds)
+		if err != nil {
+			slog.With("error", err).Error("failed to claim a job")
+		}
+
+		if job == nil {
+			select {
+			case <-q.stopping:
+				return
+			case <-time.After(q.config.JobPollInterval):
+			}
+			continue
+		}
+
+		q.run(*job)
+	}
+}
+
+// claim takes the due job that has waited the longest, or one whose worker stopped without finishing it.
+// The job is claimed with a single UPDATE that skips the rows other workers have locked,
+// so when several instances run at once each job is only claimed by one of them.
+func (q *Queue) claim(kinds []string) (*models.Job, error) {
+	now := time.Now()
+
+	due := q.db.
+		Model(&models.Job{}).
+		Select("id").
+		Where("kind IN ?", kinds).
+		Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)", models.JobPending, now, models.JobRunning, now).
+		Order("run_at asc").
+		Limit(1).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var jobs []models.Job
+	if err := q.db.
+		Model(&jobs).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Updates(map[string]interface{}{
+			"status":       models.JobRunning,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_until": now.Add(q.lease),
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	job := jobs[0]
+
+	// The run no longer holds the unique key of its recurring job, so the next run can be enqueued.
+	for _, r := range q.recurring {
+		if r.kind == job.Kind {
+			q.enqueueRecurring(r)
+		}
+	}
+
+	return &job, nil
+}
+
+// run runs a claimed job, deleting it once it succeeds, or retrying it with exponential backoff until it is dead.
+func (q *Queue) run(job models.Job) {
+	log := slog.With("job", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+	h := q.handlers[job.Kind]
+
+	ctx, cancel := context.WithTimeout(q.ctx, h.timeout)
+	start := time.Now()
+	err := call(ctx, h, job.Payload)
+	cancel()
+
+	switch {
+	case err == nil:
+		if err := q.db.Delete(&models.Job{BaseModel: models.BaseModel{ID: job.ID}}).Error; err != nil {
+			log.With("error", err).Error("failed to delete a finished job")
+			return
+		}
+		log.With("duration", time.Since(start).String()).Debug("ran job")
+	case q.ctx.Err() != nil:
+		// The server is shutting down, the attempt does not count as the job did not get the chance to finish.
+		q.requeue(job, map[string]interface{}{
+			"status":       models.JobPending,
+			"attempts":     gorm.Expr("attempts - 1"),
+			"locked_until": nil,
+			"last_error":   err.Error(),
+		})
+		log.Warn("released job interrupted by shutdown")
+	case job.UniqueKey != nil && *job.UniqueKey == recurringKey(job.Kind):
+		// Failed runs of recurring jobs are not kept, the next run does the work instead.
+		if err := q.db.Delete(&models.Job{BaseModel: models.BaseModel{ID: job.ID}}).Error; err != nil {
+			log.With("error", err).Error("failed to delete a failed run of a recurring job")
+		}
+		log.With("error", err).Error("recurring job failed, the next run tries again")
+	case job.Attempts >= job.MaxAttempts:
+		if updateErr := q.db.Model(&job).Updates(map[string]interface{}{
+			"status":       models.JobDead,
+			"locked_until": nil,
+			"last_error":   err.Error(),
+		}).Error; updateErr != nil {
+			log.With("error", updateErr).Error("failed to mark a job as dead")
+		}
+		log.With("error", err).Error("job failed and ran out of attempts")
+	default:
+		retryAt := time.Now().Add(RetryDelay(job.Attempts))
+		q.requeue(job, map[string]interface{}{
+			"status":       models.JobPending,
+			"run_at":       retryAt,
+			"locked_until": nil,
+			"last_error":   err.Error(),
+		})
+		log.With("error", err, "retry_at", retryAt).Warn("job failed")
+	}
+}
+
+// requeue puts a job back in the queue with the updates.
+// If a job with the same unique key was enqueued while it ran, that job does the work instead, so this one is deleted.
+func (q *Queue) requeue(job models.Job, updates map[string]interface{}) {
+	err := q.db.Model(&job).Updates(updates).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		err = q.db.Delete(&models.Job{BaseModel: models.BaseModel{ID: job.ID}}).Error
+	}
+	if err != nil {
+		slog.With("job", job.ID, "error", err).Error("failed to requeue a job")
+	}
+}
+
+// call runs the handler, turning a panic into an error so it is retried like any other failure.
+func call(ctx context.Context, h *handler, payload string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h.run(ctx, payload)
+}
+
+// RetryDelay is how long to wait before retrying a job that has failed attempts times.
+// The delay doubles after each attempt, up to an hour.
+func RetryDelay(attempts int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// schedule makes sure the next run of every recurring job is enqueued, until the queue stops.
+func (q *Queue) schedule() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(recurringInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range q.recurring {
+			q.enqueueRecurring(r)
+		}
+
+		select {
+		case <-q.stopping:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueRecurring enqueues the next run of the recurring job, unless it is already pending.
+func (q *Queue) enqueueRecurring(r recurring) {
+	at := r.next(time.Now())
+	if at.IsZero() {
+		slog.With("kind", r.kind).Warn("recurring job is never due")
+		return
+	}
+
+	if err := r.enqueue(q.db, at); err != nil {
+		slog.With("kind", r.kind, "error", err).Error("failed to enqueue the next run of a recurring job")
+	}
+}
@@ -0,0 +1,317 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/app/queue"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// newQueue creates a queue with a single worker over its own in-memory database.
+func newQueue(t *testing.T) (*queue.Queue, *gorm.DB) {
+	t.Helper()
+
+	conn, err := db.OpenSQLite("file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return queue.New(conn, &cfg.Configuration{
+		JobWorkers:      1,
+		JobPollInterval: 10 * time.Millisecond,
+	}), conn
+}
+
+// stop drains the queue, failing the test if the jobs do not finish in time.
+func stop(t *testing.T, q *queue.Queue) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wait waits for the value to be received, failing the test if it takes too long.
+func wait[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a job to run")
+		panic("unreachable")
+	}
+}
+
+// jobs gets every job left in the queue.
+func jobs(t *testing.T, conn *gorm.DB) []models.Job {
+	t.Helper()
+
+	var jobs []models.Job
+	if err := conn.Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return jobs
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greet = queue.NewKind[greeting]("test.greet")
+
+func TestRunJob(t *testing.T) {
+	q, conn := newQueue(t)
+
+	ran := make(chan greeting, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		ran <- payload
+		return nil
+	})
+
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	if payload := wait(t, ran); payload.Name != "alice" {
+		t.Errorf("expected the payload to be decoded, got %+v", payload)
+	}
+	stop(t, q)
+
+	// Jobs that succeed are deleted.
+	if left := jobs(t, conn); len(left) != 0 {
+		t.Errorf("expected the job to be deleted, got %+v", left)
+	}
+}
+
+func TestRunJobLater(t *testing.T) {
+	q, conn := newQueue(t)
+
+	ran := make(chan time.Time, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		ran <- time.Now()
+		return nil
+	})
+
+	runAt := time.Now().Add(200 * time.Millisecond)
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}, queue.RunAt(runAt)); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	if at := wait(t, ran); at.Before(runAt) {
+		t.Errorf("expected the job to run after %s, it ran at %s", runAt, at)
+	}
+	stop(t, q)
+}
+
+func TestUniqueKey(t *testing.T) {
+	_, conn := newQueue(t)
+
+	for _, name := range []string{"alice", "bob"} {
+		if err := greet.Enqueue(conn, greeting{Name: name}, queue.UniqueKey("greet")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := greet.Enqueue(conn, greeting{Name: "carol"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if left := jobs(t, conn); len(left) != 2 {
+		t.Errorf("expected the second job with the same key to be dropped, got %d jobs", len(left))
+	}
+}
+
+func TestFailedJob(t *testing.T) {
+	q, conn := newQueue(t)
+
+	attempts := make(chan int, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		attempts <- 1
+		panic("something went wrong")
+	})
+
+	// A single attempt, as the retries are too far apart to wait for.
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}, queue.MaxAttempts(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	wait(t, attempts)
+	stop(t, q)
+
+	left := jobs(t, conn)
+	if len(left) != 1 || left[0].Status != models.JobDead || left[0].Attempts != 1 {
+		t.Fatalf("expected the job to be dead after its only attempt, got %+v", left)
+	}
+	if left[0].LastError != "panic: something went wrong" {
+		t.Errorf("expected the panic to be recorded, got %q", left[0].LastError)
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	q, conn := newQueue(t)
+
+	attempts := make(chan int, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		attempts <- 1
+		return errors.New("mail server is down")
+	})
+
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	wait(t, attempts)
+	stop(t, q)
+
+	left := jobs(t, conn)
+	if len(left) != 1 || left[0].Status != models.JobPending || left[0].Attempts != 1 {
+		t.Fatalf("expected the job to wait to be retried, got %+v", left)
+	}
+	if retryAt := left[0].RunAt; retryAt.Before(time.Now().Add(queue.RetryDelay(1) - time.Second)) {
+		t.Errorf("expected the retry to be delayed by %s, it is at %s", queue.RetryDelay(1), retryAt)
+	}
+}
+
+func TestStopReleasesRunningJobs(t *testing.T) {
+	q, conn := newQueue(t)
+
+	started := make(chan struct{}, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	wait(t, started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain to run out of time, got %v", err)
+	}
+
+	// The interrupted attempt does not count.
+	left := jobs(t, conn)
+	if len(left) != 1 || left[0].Status != models.JobPending || left[0].Attempts != 0 {
+		t.Fatalf("expected the job to be released, got %+v", left)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		5:  160 * time.Second,
+		9:  2560 * time.Second,
+		10: time.Hour,
+		20: time.Hour,
+	}
+
+	for attempts, delay := range tests {
+		if got := queue.RetryDelay(attempts); got != delay {
+			t.Errorf("expected a delay of %s after %d attempts, got %s", delay, attempts, got)
+		}
+	}
+}
+
+func TestRecurringJob(t *testing.T) {
+	_, conn := newQueue(t)
+
+	// Every instance makes sure the next run is enqueued, but it is only enqueued once.
+	for range 2 {
+		q := queue.New(conn, &cfg.Configuration{JobWorkers: 1, JobPollInterval: 10 * time.Millisecond})
+		queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+			return nil
+		})
+		queue.Recurring(q, greet, "0 * * * *", greeting{Name: "alice"})
+
+		q.Start()
+		stop(t, q)
+	}
+
+	left := jobs(t, conn)
+	if len(left) != 1 || left[0].UniqueKey == nil || *left[0].UniqueKey != "recurring:test.greet" {
+		t.Fatalf("expected the next run to be enqueued once, got %+v", left)
+	}
+	if next := time.Now().UTC().Truncate(time.Hour).Add(time.Hour); !left[0].RunAt.Equal(next) {
+		t.Errorf("expected the next run at %s, got %s", next, left[0].RunAt)
+	}
+	if left[0].MaxAttempts != 1 {
+		t.Errorf("expected a single attempt, got %d", left[0].MaxAttempts)
+	}
+}
+
+func TestFailedRecurringJob(t *testing.T) {
+	q, conn := newQueue(t)
+
+	attempts := make(chan int, 1)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		select {
+		case attempts <- 1:
+		default:
+		}
+		return errors.New("mail server is down")
+	})
+	queue.Every(q, greet, time.Hour, greeting{Name: "alice"})
+
+	// The next run is an hour away, so enqueue a run that is due now as the scheduler would have.
+	if err := greet.Enqueue(conn, greeting{Name: "alice"}, queue.UniqueKey("recurring:test.greet"), queue.MaxAttempts(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start()
+	wait(t, attempts)
+	stop(t, q)
+
+	// The failed run is deleted rather than left dead, only the next run is left.
+	left := jobs(t, conn)
+	if len(left) != 1 || left[0].Status != models.JobPending || left[0].Attempts != 0 {
+		t.Fatalf("expected only the next run to be left, got %+v", left)
+	}
+}
+
+func TestEveryJob(t *testing.T) {
+	q, _ := newQueue(t)
+
+	ran := make(chan time.Time, 2)
+	queue.Handle(q, greet, func(ctx context.Context, payload greeting) error {
+		select {
+		case ran <- time.Now():
+		default:
+		}
+		return nil
+	})
+	queue.Every(q, greet, 100*time.Millisecond, greeting{Name: "alice"})
+
+	// Claiming a run enqueues the next one straight away, so it runs again on the next interval.
+	q.Start()
+	first, second := wait(t, ran), wait(t, ran)
+	stop(t, q)
+
+	if gap := second.Sub(first); gap < 50*time.Millisecond {
+		t.Errorf("expected the runs to be an interval apart, they were %s apart", gap)
+	}
+}
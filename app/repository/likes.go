@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+)
+
+// LikeRepository reads and writes the likes on posts.
+type LikeRepository interface {
+	// Exists checks whether the user has liked the post.
+	Exists(postID, userID string) (bool, error)
+	// Create likes the post as the user.
+	Create(postID, userID string) error
+	// Delete removes the user's like from the post, if there is one.
+	Delete(postID, userID string) error
+	// ListVisible gets the likes on the post along with who made them, hiding the likes the viewer cannot see.
+	ListVisible(postID, viewerID string) ([]models.Like, error)
+}
+
+// likes is the GORM implementation of LikeRepository.
+type likes struct {
+	db *gorm.DB
+}
+
+func (r *likes) Exists(postID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Like{}).Where(models.Like{PostID: postID, LikedByID: userID}).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *likes) Create(postID, userID string) error {
+	return r.db.Create(&models.Like{PostID: postID, LikedByID: userID}).Error
+}
+
+func (r *likes) Delete(postID, userID string) error {
+	return r.db.Where(models.Like{PostID: postID, LikedByID: userID}).Delete(&models.Like{}).Error
+}
+
+func (r *likes) ListVisible(postID, viewerID string) ([]models.Like, error) {
+	var likes []models.Like
+	err := r.db.
+		Preload("LikedBy", omitEmail).
+		// Hide the likes of protected accounts from anyone who does not follow them.
+		Scopes(models.LikesVisibleTo(viewerID)).
+		Where(models.Like{PostID: postID}).
+		Find(&likes).Error
+	return likes, err
+}
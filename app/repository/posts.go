@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+)
+
+// PostRepository reads and writes posts.
+// Every read that takes a viewer only returns posts visible to them, see models.VisibleTo.
+// Extended reads preload everything the post handlers need to extend the posts, see PreloadExtended.
+type PostRepository interface {
+	// Find gets a post by its ID regardless of who can see it, for use by its author.
+	Find(postID string) (models.Post, error)
+	// FindVisible gets a post by its ID.
+	FindVisible(postID, viewerID string) (models.Post, error)
+	// FindExtended gets a post by its ID with its relations preloaded.
+	FindExtended(postID, viewerID string) (models.Post, error)
+	// FindWithReplies gets a post by its ID with its replies and their relations preloaded,
+	// skipping replies by users the viewer has muted.
+	FindWithReplies(postID, viewerID string) (models.Post, error)
+
+	// ListTimeline gets the top level posts of the global feed, most recent first, skipping users the viewer has muted.
+	ListTimeline(viewerID string) ([]models.Post, error)
+	// ListByAuthor gets the posts made by the author, most recent first, skipping the excluded posts.
+	ListByAuthor(authorID, viewerID string, exclude []string) ([]models.Post, error)
+	// ListPinned gets the posts pinned by the user, in the order they were pinned.
+	ListPinned(userID, viewerID string) ([]models.Post, error)
+	// ListPinnedExtended gets the posts pinned by the user with their relations preloaded, in the order they were pinned.
+	ListPinnedExtended(userID, viewerID string) ([]models.Post, error)
+	// ListDrafts gets a page of the author's drafts with their relations preloaded, most recently updated first.
+	ListDrafts(authorID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error)
+	// ListScheduled gets a page of the author's scheduled posts with their relations preloaded, the next to be published first.
+	ListScheduled(authorID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error)
+	// ListBookmarked gets a page of the posts bookmarked by the user with their relations preloaded, most recently bookmarked first.
+	ListBookmarked(userID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error)
+
+	// Create creates the post along with its poll and mentions.
+	Create(post *models.Post) error
+	// Delete deletes the post along with its replies.
+	Delete(post *models.Post) error
+}
+
+// PreloadExtended preloads every relation needed to extend a post for the viewer.
+// The prefix is used to preload the relations of nested posts, such as "Replies.".
+// Only the likes visible to the viewer are preloaded, so they are counted and listed the same way as LikeRepository.ListVisible.
+func PreloadExtended(prefix, viewerID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.
+			Preload(prefix+"Likes", models.LikesVisibleTo(viewerID)).
+			Preload(prefix+"Bookmarks", "user_id = ?", viewerID). // Only the viewer's bookmarks are needed.
+			Preload(prefix+"Replies", models.VisibleTo(viewerID)).
+			Preload(prefix+"Author", omitEmail).
+			Preload(prefix+"Poll.Options", func(db *gorm.DB) *gorm.DB {
+				return db.Order("position")
+			}).
+			Preload(prefix + "Poll.Votes.Choices")
+	}
+}
+
+// posts is the GORM implementation of PostRepository.
+type posts struct {
+	db *gorm.DB
+}
+
+// byID matches the post with the ID.
+func byID(postID string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(models.Post{BaseModel: models.BaseModel{ID: postID}})
+	}
+}
+
+func (r *posts) Find(postID string) (models.Post, error) {
+	var post models.Post
+	err := r.db.Scopes(byID(postID)).First(&post).Error
+	return post, err
+}
+
+func (r *posts) FindVisible(postID, viewerID string) (models.Post, error) {
+	var post models.Post
+	err := r.db.Scopes(models.VisibleTo(viewerID), byID(postID)).First(&post).Error
+	return post, err
+}
+
+func (r *posts) FindExtended(postID, viewerID string) (models.Post, error) {
+	var post models.Post
+	err := r.db.Scopes(models.VisibleTo(viewerID), PreloadExtended("", viewerID), byID(postID)).First(&post).Error
+	return post, err
+}
+
+func (r *posts) FindWithReplies(postID, viewerID string) (models.Post, error) {
+	var post models.Post
+	err := r.db.
+		Scopes(models.VisibleTo(viewerID), PreloadExtended("Replies.", viewerID), byID(postID)).
+		Preload("Replies", models.VisibleTo(viewerID), models.NotMutedBy(viewerID)). // Only replies visible to the viewer are listed.
+		First(&post).Error
+	return post, err
+}
+
+func (r *posts) ListTimeline(viewerID string) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.
+		Scopes(models.VisibleTo(viewerID), models.NotMutedBy(viewerID), PreloadExtended("", viewerID)).
+		// only get top level posts
+		Where("parent_id IS NULL").
+		Order("created_at desc").
+		Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListByAuthor(authorID, viewerID string, exclude []string) ([]models.Post, error) {
+	tx := r.db.
+		Scopes(models.VisibleTo(viewerID), PreloadExtended("", viewerID)).
+		Where(models.Post{AuthorID: authorID})
+	if len(exclude) > 0 {
+		tx = tx.Where("posts.id NOT IN ?", exclude)
+	}
+
+	var posts []models.Post
+	err := tx.Order("created_at desc").Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListPinned(userID, viewerID string) ([]models.Post, error) {
+	posts := []models.Post{}
+	err := r.db.Scopes(models.VisibleTo(viewerID), models.PinnedBy(userID)).Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListPinnedExtended(userID, viewerID string) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Scopes(models.VisibleTo(viewerID), PreloadExtended("", viewerID), models.PinnedBy(userID)).Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListDrafts(authorID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.
+		Scopes(PreloadExtended("", authorID), page).
+		Where(models.Post{AuthorID: authorID, Status: models.PostStatusDraft}).
+		Order("updated_at desc").
+		Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListScheduled(authorID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.
+		Scopes(PreloadExtended("", authorID), page).
+		Where(models.Post{AuthorID: authorID, Status: models.PostStatusScheduled}).
+		Order("publish_at asc").
+		Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) ListBookmarked(userID string, page func(*gorm.DB) *gorm.DB) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.
+		Scopes(models.VisibleTo(userID), PreloadExtended("", userID)).
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id AND bookmarks.user_id = ?", userID).
+		Order("bookmarks.created_at desc").
+		Scopes(page).
+		Find(&posts).Error
+	return posts, err
+}
+
+func (r *posts) Create(post *models.Post) error {
+	return r.db.Create(post).Error
+}
+
+func (r *posts) Delete(post *models.Post) error {
+	return r.db.Delete(post).Error
+}
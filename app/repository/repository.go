@@ -0,0 +1,30 @@
+// Package repository wraps the queries of the application's core models behind interfaces.
+//
+// The implementations only use portable GORM queries and the scopes in the models package, so the same repositories
+// work against Postgres in production and SQLite in tests.
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories holds a repository for each of the core models, all backed by the same database.
+type Repositories struct {
+	Users    UserRepository
+	Posts    PostRepository
+	Likes    LikeRepository
+	Sessions SessionRepository
+}
+
+// New creates the repositories backed by the database.
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:    &users{db: db},
+		Posts:    &posts{db: db},
+		Likes:    &likes{db: db},
+		Sessions: &sessions{db: db},
+	}
+}
+
+// omitEmail omits the email of preloaded users for privacy reasons.
+func omitEmail(tx *gorm.DB) *gorm.DB {
+	return tx.Omit("Email")
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// SessionRepository reads and writes the sessions users are signed in with, identified by their token.
+type SessionRepository interface {
+	// Find gets a session by its token, along with its connection and user.
+	// Expired sessions are returned as well, it is up to the caller to check ExpiresAt.
+	Find(token string) (models.Session, error)
+	// Create creates the session for a connection.
+	Create(session *models.Session) error
+	// Delete deletes the session, signing the user out.
+	Delete(token string) error
+	// PinToPrimary pins the reads of every unexpired session of the user to the primary database until the time.
+	PinToPrimary(userID string, until time.Time) error
+}
+
+// sessions is the GORM implementation of SessionRepository.
+type sessions struct {
+	db *gorm.DB
+}
+
+func (r *sessions) Find(token string) (models.Session, error) {
+	var session models.Session
+	err := r.db.
+		Preload("Connection").
+		Preload("Connection.User").
+		Where(models.Session{BaseModel: models.BaseModel{ID: token}}).
+		First(&session).Error
+	return session, err
+}
+
+func (r *sessions) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessions) Delete(token string) error {
+	return r.db.Delete(&models.Session{BaseModel: models.BaseModel{ID: token}}).Error
+}
+
+func (r *sessions) PinToPrimary(userID string, until time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("connection_id IN (?)", r.db.Model(&models.Connection{}).Select("id").Where("user_id = ?", userID)).
+		Where("expires_at > ?", time.Now()).
+		UpdateColumn("primary_until", until).Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+)
+
+// UserRepository reads and writes users along with their connections.
+// Users are returned without their email unless stated otherwise.
+type UserRepository interface {
+	// List gets every user.
+	List() ([]models.User, error)
+	// FindByUsername gets a user by their username.
+	FindByUsername(username string) (models.User, error)
+
+	// EmailTaken checks whether a user has already registered with the email.
+	EmailTaken(email string) (bool, error)
+	// UsernameTaken checks whether a user has already registered with the username.
+	UsernameTaken(username string) (bool, error)
+	// Create creates the user along with any connections and sessions attached to it.
+	Create(user *models.User) error
+
+	// FindConnection gets a connection by its ID, such as email:user@example.com.
+	FindConnection(id string) (models.Connection, error)
+	// UpdateConnection saves the changed fields of the connection.
+	UpdateConnection(connection *models.Connection) error
+}
+
+// users is the GORM implementation of UserRepository.
+type users struct {
+	db *gorm.DB
+}
+
+func (r *users) List() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Scopes(omitEmail).Find(&users).Error
+	return users, err
+}
+
+func (r *users) FindByUsername(username string) (models.User, error) {
+	var user models.User
+	err := r.db.Scopes(omitEmail).Where(models.User{Username: username}).First(&user).Error
+	return user, err
+}
+
+func (r *users) EmailTaken(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where(models.User{Email: email}).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *users) UsernameTaken(username string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where(models.User{Username: username}).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *users) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *users) FindConnection(id string) (models.Connection, error) {
+	var connection models.Connection
+	err := r.db.Where(models.Connection{BaseModel: models.BaseModel{ID: id}}).First(&connection).Error
+	return connection, err
+}
+
+func (r *users) UpdateConnection(connection *models.Connection) error {
+	return r.db.Updates(connection).Error
+}
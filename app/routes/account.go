@@ -2,16 +2,50 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/app/handlers/account"
 	"github.com/twibber/core/app/handlers/auth"
+	"github.com/twibber/core/app/handlers/lists"
+	"github.com/twibber/core/app/handlers/posts"
 )
 
-func AccountRoutes(api fiber.Router) {
+func AccountRoutes(api fiber.Router, a *app.App) {
+	account := account.New(a)
+	auth := auth.New(a)
+	lists := lists.New(a)
+	posts := posts.New(a)
+
 	// Account
 	api.Get("/", account.GetSession)
 	api.Patch("/password", account.UpdatePassword)
 	api.Patch("/", account.UpdateProfile)
 
+	// Follow requests for protected accounts
+	followRequests := api.Group("/follow-requests")
+	{
+		followRequests.Get("/", account.ListFollowRequests)
+		followRequests.Post("/:request/approve", account.ApproveFollowRequest)
+		followRequests.Post("/:request/reject", account.RejectFollowRequest)
+	}
+
+	// Keyword and phrase mute filters
+	filters := api.Group("/filters")
+	{
+		filters.Get("/", account.ListFilters)
+		filters.Post("/", account.CreateFilter)
+		filters.Delete("/:filter", account.DeleteFilter)
+	}
+
+	// Bookmarks
+	api.Get("/bookmarks", posts.ListBookmarks)
+
+	// Lists, including private lists
+	api.Get("/lists", lists.ListOwnLists)
+
+	// Unpublished posts
+	api.Get("/drafts", posts.ListDrafts)
+	api.Get("/scheduled", posts.ListScheduled)
+
 	// Verification Flow
 	api.Post("/verify", auth.Verify)
 	api.Post("/resend", auth.ResendCode)
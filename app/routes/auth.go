@@ -2,10 +2,13 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/app/handlers/auth"
 )
 
-func AuthRoutes(api fiber.Router) {
+func AuthRoutes(api fiber.Router, a *app.App) {
+	auth := auth.New(a)
+
 	// Authentication Flow
 	api.Post("/login", auth.Login)
 	api.Post("/register", auth.Register)
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/messages"
+	"github.com/twibber/core/app/middleware"
+)
+
+func ConversationRoutes(api fiber.Router, a *app.App) {
+	messages := messages.New(a)
+	middleware := middleware.New(a)
+
+	api.Get("/", messages.ListConversations)                          // List conversations, most recently active first
+	api.Post("/", middleware.Auth(true), messages.CreateConversation) // Require a verified account to start a conversation
+
+	conversation := api.Group("/:conversation")
+	{
+		conversation.Get("/", messages.GetConversation)         // Get a single conversation by its ID
+		conversation.Post("/read", messages.ReadConversation)   // Mark every message in the conversation as read
+		conversation.Post("/leave", messages.LeaveConversation) // Leave the conversation
+
+		msgs := conversation.Group("/messages")
+		{
+			msgs.Get("/", messages.ListMessages)                        // List the messages in the conversation
+			msgs.Post("/", middleware.Auth(true), messages.SendMessage) // Require a verified account to send a message
+			msgs.Delete("/:message", messages.DeleteMessage)            // Delete a message for the current user only
+		}
+	}
+}
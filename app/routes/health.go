@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/health"
+)
+
+func HealthRoutes(api fiber.Router, a *app.App) {
+	health := health.New(a)
+
+	api.Get("/live", health.Live)   // The process is up, for liveness probes
+	api.Get("/ready", health.Ready) // The database is reachable, with connection pool statistics for metrics
+}
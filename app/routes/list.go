@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/lists"
+	"github.com/twibber/core/app/handlers/posts"
+	"github.com/twibber/core/app/middleware"
+)
+
+func ListRoutes(api fiber.Router, a *app.App) {
+	lists := lists.New(a)
+	posts := posts.New(a)
+	middleware := middleware.New(a)
+
+	api.Post("/", middleware.Auth(false), lists.CreateList) // Create a list
+
+	list := api.Group("/:list")
+	{
+		list.Get("/", lists.GetList)                               // Get a single list, private lists are only visible to their owner
+		list.Patch("/", middleware.Auth(false), lists.UpdateList)  // Update a list
+		list.Delete("/", middleware.Auth(false), lists.DeleteList) // Delete a list
+		list.Get("/timeline", posts.ListTimeline)                  // List the posts of the accounts in the list
+
+		members := list.Group("/members")
+		{
+			members.Get("/", lists.ListMembers)                                  // List the accounts in the list
+			members.Post("/:user", middleware.Auth(false), lists.AddMember)      // Add an account to the list
+			members.Delete("/:user", middleware.Auth(false), lists.RemoveMember) // Remove an account from the list
+		}
+	}
+}
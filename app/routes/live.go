@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/live"
+)
+
+func LiveRoutes(api fiber.Router, a *app.App) {
+	live := live.New(a)
+
+	api.Use(live.Upgrade)                  // Only allow WebSocket upgrades, authentication is optional
+	api.Get("/", websocket.New(live.Live)) // WebSocket for live thread updates and presence
+}
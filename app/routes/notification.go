@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/notifications"
+)
+
+func NotificationRoutes(api fiber.Router, a *app.App) {
+	notifications := notifications.New(a)
+
+	api.Get("/", notifications.ListNotifications)      // List notifications with the unread count
+	api.Post("/read", notifications.ReadNotifications) // Mark notifications as read up to a cursor
+}
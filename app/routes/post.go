@@ -2,18 +2,25 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/app/handlers/posts"
 	"github.com/twibber/core/app/middleware"
 )
 
-func PostRoutes(api fiber.Router) {
+func PostRoutes(api fiber.Router, a *app.App) {
+	posts := posts.New(a)
+	middleware := middleware.New(a)
+
 	api.Get("/", posts.ListPosts)                          // Get all posts
 	api.Post("/", middleware.Auth(true), posts.CreatePost) // Require authentication and a verified account to create a post
 
 	post := api.Group("/:post")
 	{
-		post.Get("/", posts.GetPost)                              // Get a single post by its ID
-		post.Delete("/", middleware.Auth(true), posts.DeletePost) // Require authentication and a verified account to delete a post
+		post.Get("/", posts.GetPost)                                    // Get a single post by its ID
+		post.Delete("/", middleware.Auth(true), posts.DeletePost)       // Require authentication and a verified account to delete a post
+		post.Post("/publish", middleware.Auth(true), posts.PublishPost) // Publish a draft or scheduled post straight away
+		post.Post("/pin", middleware.Auth(true), posts.PinPost)         // Pin a post to the author's profile
+		post.Delete("/pin", middleware.Auth(true), posts.UnpinPost)     // Unpin a post from the author's profile
 
 		replies := post.Group("/replies")
 		{
@@ -27,5 +34,16 @@ func PostRoutes(api fiber.Router) {
 			likes.Post("/", middleware.Auth(true), posts.LikePost)     // Like a post
 			likes.Delete("/", middleware.Auth(true), posts.UnlikePost) // Unlike a post
 		}
+
+		poll := post.Group("/poll")
+		{
+			poll.Post("/votes", middleware.Auth(true), posts.VotePoll) // Vote in the poll attached to a post
+		}
+
+		bookmark := post.Group("/bookmark")
+		{
+			bookmark.Post("/", middleware.Auth(true), posts.BookmarkPost)     // Bookmark a post
+			bookmark.Delete("/", middleware.Auth(true), posts.UnbookmarkPost) // Remove a bookmark from a post
+		}
 	}
 }
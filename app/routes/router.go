@@ -3,19 +3,24 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/app/middleware"
 	"github.com/twibber/core/cfg"
 	"github.com/twibber/core/utils"
 	"log/slog"
+	"net/url"
+	"slices"
 	"strings"
 )
 
 // Configure sets up the Fiber application with various middleware and routes.
-func Configure() *fiber.App {
+func Configure(a *app.App) *fiber.App {
+	middleware := middleware.New(a)
+
 	// Create a new fiber instance
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
-		ServerHeader:          cfg.Config.Name,
+		ServerHeader:          a.Config.Name,
 		// error handler
 		ErrorHandler: utils.ErrorHandler,
 	})
@@ -32,11 +37,14 @@ func Configure() *fiber.App {
 	// Apply the CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOriginsFunc: func(origin string) bool {
-			return strings.Contains(origin, cfg.Config.Domain)
+			return allowOrigin(a.Config, origin)
 		},
 		AllowCredentials: true,
 	}))
 
+	// Pin the reads of clients that just wrote to the primary database
+	app.Use(middleware.ReadYourWrites())
+
 	// Define a simple route to test the server is running
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -44,14 +52,41 @@ func Configure() *fiber.App {
 		})
 	})
 
+	// Health checks for load balancers and orchestrators
+	HealthRoutes(app.Group("/health"), a)
+
 	// Initiate sub-routers
-	AuthRoutes(app.Group("/auth"))
-	AccountRoutes(app.Group("/account", middleware.Auth(false)))
+	AuthRoutes(app.Group("/auth"), a)
+	AccountRoutes(app.Group("/account", middleware.Auth(false)), a)
+	NotificationRoutes(app.Group("/notifications", middleware.Auth(false)), a)
+	ConversationRoutes(app.Group("/conversations", middleware.Auth(false)), a)
 
 	// No authentication required to view posts, handling inside the subrouters
-	PostRoutes(app.Group("/posts"))
-	UserRoutes(app.Group("/users"))
+	PostRoutes(app.Group("/posts"), a)
+	UserRoutes(app.Group("/users"), a)
+	SearchRoutes(app.Group("/search"), a)
+	ListRoutes(app.Group("/lists"), a)
+
+	// Authentication is optional, the user channel is only available with a session
+	StreamRoutes(app.Group("/stream"), a)
+	LiveRoutes(app.Group("/live"), a)
 
 	// Return the configured app for the webserver to start listening
 	return app
 }
+
+// allowOrigin checks whether the origin can make credentialed cross-origin requests.
+// The configured origins must match exactly, otherwise the origin must be on the domain or one of its subdomains.
+func allowOrigin(config *cfg.Configuration, origin string) bool {
+	if len(config.CORSOrigins) > 0 {
+		return slices.Contains(config.CORSOrigins, origin)
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	host := u.Hostname()
+	return host == config.Domain || strings.HasSuffix(host, "."+config.Domain)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/posts"
+)
+
+func SearchRoutes(api fiber.Router, a *app.App) {
+	posts := posts.New(a)
+
+	api.Get("/posts", posts.SearchPosts) // Full-text search across all posts
+}
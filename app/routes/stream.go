@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/handlers/stream"
+)
+
+func StreamRoutes(api fiber.Router, a *app.App) {
+	stream := stream.New(a)
+
+	api.Get("/", stream.Stream) // Server-Sent Events stream of the user and public channels
+}
@@ -2,17 +2,33 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/twibber/core/app"
 	"github.com/twibber/core/app/handlers/posts"
 	"github.com/twibber/core/app/handlers/users"
+	"github.com/twibber/core/app/middleware"
 )
 
-func UserRoutes(api fiber.Router) {
-	api.Get("/", users.ListUsers) // Get all users
+func UserRoutes(api fiber.Router, a *app.App) {
+	posts := posts.New(a)
+	users := users.New(a)
+	middleware := middleware.New(a)
+
+	api.Get("/", users.ListUsers)         // Get all users
+	api.Get("/search", users.SearchUsers) // Search users by username and display name, must be registered before /:user
 
 	// User specific routes
 	userRouter := api.Group("/:user")
 	{
 		userRouter.Get("/", users.GetUser)           // Get user profile
 		userRouter.Get("/posts", posts.GetUserPosts) // Get user posts
+
+		userRouter.Post("/follow", middleware.Auth(true), users.FollowUser)     // Follow a user, or request to follow a protected account
+		userRouter.Delete("/follow", middleware.Auth(true), users.UnfollowUser) // Unfollow a user, or cancel a follow request
+
+		// Blocking and muting do not require a verified account, so anyone can protect themselves from harassment
+		userRouter.Post("/block", middleware.Auth(false), users.BlockUser)
+		userRouter.Delete("/block", middleware.Auth(false), users.UnblockUser)
+		userRouter.Post("/mute", middleware.Auth(false), users.MuteUser)
+		userRouter.Delete("/mute", middleware.Auth(false), users.UnmuteUser)
 	}
 }
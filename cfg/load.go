@@ -0,0 +1,178 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error lists every problem found while loading the configuration.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlType      = reflect.TypeOf(url.URL{})
+)
+
+// LoadConfiguration loads the configuration from environment variables into the struct fields with an env tag.
+//
+// Fields can be strings, booleans, integers, durations such as "5m", comma separated lists of strings and URLs.
+// The default tag is used when the variable is unset or empty, and the required tag is either "true",
+// "unless=Field" or "if=Field" to only require the key depending on a boolean field, such as the mail settings
+// only being required when DEBUG is false.
+//
+// Every problem is collected and returned together as an *Error, rather than stopping at the first.
+func LoadConfiguration(config *Configuration) error {
+	val := reflect.ValueOf(config).Elem()
+
+	var problems []string
+	invalid := make(map[string]bool)
+
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		env := typeField.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		value := os.Getenv(env)
+		if value == "" {
+			value = typeField.Tag.Get("default")
+		}
+
+		if value == "" {
+			val.Field(i).SetZero()
+			continue
+		}
+
+		if err := setField(val.Field(i), value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", env, err))
+			invalid[env] = true
+		}
+	}
+
+	// Required keys are checked once every field is loaded, so the conditions can depend on any of them.
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		env := typeField.Tag.Get("env")
+		required := typeField.Tag.Get("required")
+		if env == "" || required == "" || invalid[env] {
+			continue
+		}
+
+		needed, err := isRequired(val, required)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", env, err))
+			continue
+		}
+
+		if needed && val.Field(i).IsZero() {
+			problems = append(problems, fmt.Sprintf("%s: is required", env))
+			invalid[env] = true
+		}
+	}
+
+	// Check the values that depend on more than one key, skipping problems with keys that already have one.
+	for _, p := range config.validate() {
+		if !slices.ContainsFunc(p.keys, func(key string) bool { return invalid[key] }) {
+			problems = append(problems, strings.Join(p.keys, " and ")+": "+p.problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+
+	return nil
+}
+
+// setField parses the value into the field based on its type.
+func setField(field reflect.Value, value string) error {
+	switch field.Type() {
+	case durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 5m")
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case urlType:
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("must be an absolute URL")
+		}
+		field.Set(reflect.ValueOf(*parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// isRequired evaluates a required tag against the loaded configuration.
+func isRequired(val reflect.Value, required string) (bool, error) {
+	if required == "true" {
+		return true, nil
+	}
+
+	condition, name, ok := strings.Cut(required, "=")
+	if !ok {
+		return false, errors.New("invalid required tag " + required)
+	}
+
+	other := val.FieldByName(name)
+	if !other.IsValid() || other.Kind() != reflect.Bool {
+		return false, errors.New("required tag refers to unknown boolean field " + name)
+	}
+
+	switch condition {
+	case "if":
+		return other.Bool(), nil
+	case "unless":
+		return !other.Bool(), nil
+	default:
+		return false, errors.New("invalid required tag " + required)
+	}
+}
@@ -1,50 +1,212 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	_ "gorm.io/gorm/logger"
 	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/twibber/core/cfg"
 )
 
-// DB is the global variable used to use GORM
-var DB *gorm.DB
-
-// init the database connection
-func init() {
-	// Create the connection URL
-	connUrl := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s",
-		cfg.Config.DBUsername,
-		cfg.Config.DBPassword,
-		cfg.Config.DBHost,
-		cfg.Config.DBPort,
-		cfg.Config.DBDatabase,
-	)
-
-	// Connect to the database via GORM
-	if conn, err := gorm.Open(postgres.Open(connUrl), &gorm.Config{
+// The delay between connection attempts on start doubles after each failure, up to maxRetryDelay.
+const (
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
+
+// DSN builds the connection URL for the database described by the configuration.
+// Every value is escaped by net/url, so credentials containing spaces, quotes or other special characters are safe.
+func DSN(config *cfg.Configuration) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(config.DBUsername, config.DBPassword),
+		Host:   net.JoinHostPort(config.DBHost, strconv.Itoa(config.DBPort)),
+		Path:   "/" + config.DBDatabase,
+	}
+	if config.DBPassword == "" {
+		u.User = url.User(config.DBUsername)
+	}
+
+	u.RawQuery = connectionParams(config, url.Values{}).Encode()
+
+	return u.String()
+}
+
+// ReplicaDSN adds the connection settings of the configuration to the connection URL of a read replica.
+// Settings already given in the replica's URL, such as its own sslmode, are left as they are.
+func ReplicaDSN(config *cfg.Configuration, replica string) (string, error) {
+	u, err := url.Parse(replica)
+	if err != nil {
+		return "", err
+	}
+
+	u.RawQuery = connectionParams(config, u.Query()).Encode()
+
+	return u.String(), nil
+}
+
+// connectionParams adds the connection settings of the configuration to the query parameters, unless they are set.
+func connectionParams(config *cfg.Configuration, query url.Values) url.Values {
+	params := map[string]string{
+		"sslmode":          config.DBSSLMode,
+		"connect_timeout":  strconv.Itoa(int(config.DBConnectTimeout / time.Second)),
+		"application_name": config.Name,
+	}
+
+	// Unknown parameters are sent to Postgres as session settings, so the timeout applies to every connection.
+	if config.DBStatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(config.DBStatementTimeout.Milliseconds(), 10)
+	}
+
+	for key, value := range params {
+		if !query.Has(key) {
+			query.Set(key, value)
+		}
+	}
+
+	return query
+}
+
+// Open connects to the database described by the configuration and configures its connection pool.
+// Postgres is often still starting alongside the application, so failed connections are retried with backoff until
+// DB_STARTUP_TIMEOUT has passed.
+func Open(config *cfg.Configuration) (*gorm.DB, error) {
+	conn, attempts, err := connect(DSN(config), config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the database connection
+	slog.With(slog.String("host", config.DBHost),
+		slog.Int("port", config.DBPort),
+		slog.String("username", config.DBUsername),
+		slog.String("database", config.DBDatabase),
+		slog.Int("attempts", attempts),
+	).Info("initiated database connection")
+
+	return conn, nil
+}
+
+// OpenReplicas connects to each of the configured read replicas, with the same pool settings and retries as the primary.
+func OpenReplicas(config *cfg.Configuration) ([]*gorm.DB, error) {
+	var replicas []*gorm.DB
+	for i, replica := range config.DBReplicas {
+		dsn, err := ReplicaDSN(config, replica)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+
+		conn, attempts, err := connect(dsn, config)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+
+		// Only log the host, the URL may contain credentials.
+		u, _ := url.Parse(replica)
+		slog.With(slog.Int("replica", i+1),
+			slog.String("host", u.Host),
+			slog.Int("attempts", attempts),
+		).Info("initiated read replica connection")
+
+		replicas = append(replicas, conn)
+	}
+
+	return replicas, nil
+}
+
+// connect connects to the database, retrying with backoff, and returns the number of attempts it took.
+func connect(dsn string, config *cfg.Configuration) (*gorm.DB, int, error) {
+	deadline := time.Now().Add(config.DBStartupTimeout)
+	delay := initialRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		conn, err := open(dsn, config)
+		if err == nil {
+			return conn, attempt, nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return nil, attempt, err
+		}
+
+		slog.With("attempt", attempt, "retry_in", delay.String(), "error", err).Warn("failed to connect to the database")
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// open makes a single attempt to connect to the database, and applies the pool settings once connected.
+func open(dsn string, config *cfg.Configuration) (*gorm.DB, error) {
+	// Connect to the database via GORM, which pings the database to make sure it is reachable
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		FullSaveAssociations: true,
-	}); err != nil {
-		// if an error occurs panic, which will cause the application to crash before the webserver is started
-		panic(err)
-	} else {
-		// Log the database connection
-		slog.With(slog.String("host", cfg.Config.DBHost),
-			slog.String("port", cfg.Config.DBPort),
-			slog.String("username", cfg.Config.DBUsername),
-			slog.String("database", cfg.Config.DBDatabase),
-		).Info("initiated database connection")
+		TranslateError:       true, // translate driver errors such as unique violations into GORM errors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB.SetMaxOpenConns(config.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.DBConnMaxIdleTime)
+
+	return conn, nil
+}
 
-		DB = conn
+// PoolStats are the statistics of a database connection pool, for metrics and health checks.
+type PoolStats struct {
+	MaxOpenConnections int `json:"max_open_connections"` // Zero means unlimited.
+
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+
+	WaitCount         int64         `json:"wait_count"`           // Connections waited for because the pool was exhausted.
+	WaitDuration      time.Duration `json:"wait_duration_ns"`     // Total time spent waiting for connections.
+	MaxIdleClosed     int64         `json:"max_idle_closed"`      // Connections closed because of the idle limit.
+	MaxIdleTimeClosed int64         `json:"max_idle_time_closed"` // Connections closed because they were idle too long.
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"`  // Connections closed because they reached their lifetime.
+}
+
+// Stats gets the statistics of the database's connection pool.
+func Stats(db *gorm.DB) (PoolStats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return PoolStats{}, err
 	}
 
-	if cfg.Config.Debug {
-		DB.Debug()
+	stats := sqlDB.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}, nil
+}
+
+// Ping checks the database is reachable within the context's deadline.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
 	}
 
-	// Post connection we can migrate the database.
-	MigrateDB()
+	return sqlDB.PingContext(ctx)
 }
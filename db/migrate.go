@@ -1,24 +1,356 @@
 package db
 
 import (
+	"embed"
+	"errors"
+	"fmt"
 	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+	"io/fs"
 	"log/slog"
-	"reflect"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 )
 
-// MigrateDB migrates models into the database.
-func MigrateDB() {
-	// spread the models into the AutoMigrate function, so that all models are migrated.
-	if err := DB.Migrator().AutoMigrate(models.Models...); err != nil {
-		panic(err)
+// migrationFiles are the SQL migrations, named <version>_<name>.up.sql and <version>_<name>.down.sql.
+// Versions must be unique and are applied in ascending order, every migration needs both an up and a down step.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the key of the advisory lock held while migrating, so only one instance migrates at a time.
+const migrationLock = 7_340_512_046
+
+// migrationName matches the file names of the migrations.
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned change to the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records a migration that has been applied to the database.
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus is a migration along with when it was applied, if it has been.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations reads the embedded migrations, ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
 	}
 
-	// collect the names of the models that were migrated.
-	modelNames := make([]string, 0)
-	for _, n := range models.Models {
-		modelNames = append(modelNames, reflect.TypeOf(n).Elem().Name())
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.up.sql or <version>_<name>.down.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
 	}
 
-	// log the models that were migrated.
-	slog.With("models", modelNames).Info("database migrated successfully")
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: must have both an up and a down step", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies and rolls back the migrations of a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the database with the embedded migrations.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// locked runs fn on a single connection while holding the migration lock, creating the schema_migrations table if needed.
+// Other instances wait for the lock, then see the migrations that were applied while they waited.
+//
+// The statement timeout is lifted on the connection while it is held, as waiting for the lock and running a migration
+// can both take longer than it. The connection is returned to the pool afterwards, so the timeout is reset.
+func (m *Migrator) locked(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET statement_timeout = 0").Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err := conn.Exec("RESET statement_timeout").Error; err != nil {
+				slog.With("error", err).Error("failed to reset the statement timeout after migrating")
+			}
+		}()
+
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLock).Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", migrationLock).Error; err != nil {
+				slog.With("error", err).Error("failed to release the migration lock")
+			}
+		}()
+
+		if err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint PRIMARY KEY,
+			name       text NOT NULL,
+			applied_at timestamptz NOT NULL
+		)`).Error; err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+// applied gets the applied migrations by version.
+func applied(conn *gorm.DB) (map[int64]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := conn.Order("version asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	versions := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		versions[row.Version] = row
+	}
+
+	return versions, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns the migrations applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			slog.With("version", migration.Version, "name", migration.Name).Info("applied migration")
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back the latest applied migrations, at most steps of them, and returns the migrations rolled back.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+
+				return tx.Delete(&SchemaMigration{Version: migration.Version}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			slog.With("version", migration.Version, "name", migration.Name).Info("rolled back migration")
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Status lists every migration along with when it was applied.
+// Migrations recorded in the database that are not known to this build are an error, as the schema is newer than the code.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(func(conn *gorm.DB) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if row, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &row.AppliedAt
+				delete(versions, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+
+		if len(versions) > 0 {
+			return fmt.Errorf("%d applied migrations are not known to this build, the schema is newer than the code", len(versions))
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Pending counts the migrations that have not been applied yet.
+func (m *Migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+// ErrSchemaMismatch is returned by Check when the models do not agree with the migrated schema.
+var ErrSchemaMismatch = errors.New("the models do not agree with the migrated schema")
+
+// Check makes sure every table, column and index the models expect exists in the database, returning the problems found.
+// It should be run against a database with every migration applied, so a model changed without a migration is caught.
+func (m *Migrator) Check() ([]string, error) {
+	var problems []string
+	for _, model := range models.Models {
+		stmt := &gorm.Statement{DB: m.db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+
+		table := stmt.Schema.Table
+		if !m.db.Migrator().HasTable(model) {
+			problems = append(problems, fmt.Sprintf("%s: table is missing", table))
+			continue
+		}
+
+		for _, column := range stmt.Schema.DBNames {
+			if !m.db.Migrator().HasColumn(model, column) {
+				problems = append(problems, fmt.Sprintf("%s.%s: column is missing", table, column))
+			}
+		}
+
+		for name := range stmt.Schema.ParseIndexes() {
+			if !m.db.Migrator().HasIndex(model, name) {
+				problems = append(problems, fmt.Sprintf("%s: index %s is missing", table, name))
+			}
+		}
+	}
+
+	sort.Strings(problems)
+	if len(problems) > 0 {
+		return problems, ErrSchemaMismatch
+	}
+
+	return nil, nil
+}
+
+// MigrateDB applies the pending migrations, then warns about anything the models expect that the schema is missing.
+func MigrateDB(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	done, err := migrator.Up()
+	if err != nil {
+		return err
+	}
+
+	problems, err := migrator.Check()
+	if err != nil && !errors.Is(err, ErrSchemaMismatch) {
+		return err
+	}
+	for _, problem := range problems {
+		slog.With("problem", problem).Warn("the models do not agree with the migrated schema")
+	}
+
+	// log the migrations that were applied.
+	slog.With("applied", len(done), "migrations", len(migrator.migrations)).Info("database migrated successfully")
+
+	return nil
 }
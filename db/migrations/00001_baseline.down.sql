@@ -0,0 +1,29 @@
+-- Dropping the baseline removes every table, and with them all of the data.
+-- The pg_trgm extension is left in place, as other databases on the server may rely on it.
+
+DROP TABLE IF EXISTS
+    list_members,
+    lists,
+    message_deletions,
+    messages,
+    conversation_members,
+    conversations,
+    notification_actors,
+    notifications,
+    mute_filters,
+    mutes,
+    blocks,
+    follow_requests,
+    follows,
+    poll_choices,
+    poll_votes,
+    poll_options,
+    polls,
+    pins,
+    bookmarks,
+    mentions,
+    likes,
+    posts,
+    sessions,
+    connections,
+    users;
@@ -0,0 +1,269 @@
+-- The baseline schema, as previously created by AutoMigrate on boot.
+-- Every statement is conditional, so databases created before versioned migrations adopt it without changes.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                text PRIMARY KEY,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    username          varchar(64)  NOT NULL UNIQUE,
+    display_name      varchar(512),
+    protected         boolean      NOT NULL DEFAULT false,
+    dm_followers_only boolean      NOT NULL DEFAULT false,
+    email             varchar(255) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS connections (
+    id          text PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    password    varchar(512),
+    verified    boolean DEFAULT false,
+    totp_verify varchar(512),
+    user_id     text NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id            text PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    connection_id text        NOT NULL REFERENCES connections (id) ON DELETE CASCADE,
+    expires_at    timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    author_id  text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content    varchar(512),
+    status     varchar(16) NOT NULL DEFAULT 'published',
+    publish_at timestamptz,
+    visibility varchar(16) NOT NULL DEFAULT 'public',
+    parent_id  text REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status);
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at);
+
+-- Full-text search column on posts, kept up to date by Postgres itself.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search);
+
+CREATE TABLE IF NOT EXISTS likes (
+    id          text PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    liked_by_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id     text NOT NULL REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mentions (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    post_id    text NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_post_user ON mentions (post_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
+
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id    text NOT NULL REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_user_post ON bookmarks (user_id, post_id);
+
+CREATE TABLE IF NOT EXISTS pins (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id    text NOT NULL REFERENCES posts (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pins_user_post ON pins (user_id, post_id);
+
+CREATE TABLE IF NOT EXISTS polls (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    post_id    text        NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    multiple   boolean     NOT NULL DEFAULT false,
+    expires_at timestamptz NOT NULL,
+    duration   bigint      NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_polls_post_id ON polls (post_id);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    poll_id    text        NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    position   bigint      NOT NULL,
+    text       varchar(64) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poll_options_poll_position ON poll_options (poll_id, position);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    poll_id    text NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poll_votes_poll_user ON poll_votes (poll_id, user_id);
+
+CREATE TABLE IF NOT EXISTS poll_choices (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    vote_id    text NOT NULL REFERENCES poll_votes (id) ON DELETE CASCADE,
+    option_id  text NOT NULL REFERENCES poll_options (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poll_choices_vote_option ON poll_choices (vote_id, option_id);
+
+CREATE TABLE IF NOT EXISTS follows (
+    id           text PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    follower_id  text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    following_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follows_follower_following ON follows (follower_id, following_id);
+CREATE INDEX IF NOT EXISTS idx_follows_following_id ON follows (following_id);
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id           text PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    requester_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    target_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follow_requests_requester_target ON follow_requests (requester_id, target_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_target_id ON follow_requests (target_id);
+
+CREATE TABLE IF NOT EXISTS blocks (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    blocker_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blocks_blocker_blocked ON blocks (blocker_id, blocked_id);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS mutes (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    muter_id   text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    muted_id   text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mutes_muter_muted ON mutes (muter_id, muted_id);
+
+CREATE TABLE IF NOT EXISTS mute_filters (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id    text         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    keyword    varchar(128) NOT NULL,
+    scope      varchar(16)  NOT NULL DEFAULT 'all',
+    action     varchar(16)  NOT NULL DEFAULT 'hide',
+    expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_mute_filters_user_id ON mute_filters (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    recipient_id    text         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type            varchar(32)  NOT NULL,
+    group_key       varchar(128) NOT NULL,
+    post_id         text REFERENCES posts (id) ON DELETE CASCADE,
+    latest_actor_id text         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    actor_count     bigint       NOT NULL DEFAULT 0,
+    latest_at       timestamptz  NOT NULL,
+    read_at         timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_id ON notifications (recipient_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unread_group ON notifications (recipient_id, type, group_key) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_latest_at ON notifications (latest_at);
+
+CREATE TABLE IF NOT EXISTS notification_actors (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    notification_id text NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    actor_id        text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_actors_notification_actor ON notification_actors (notification_id, actor_id);
+
+CREATE TABLE IF NOT EXISTS conversations (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    direct_key      varchar(128),
+    name            varchar(64),
+    last_message_at timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_direct_key ON conversations (direct_key);
+CREATE INDEX IF NOT EXISTS idx_conversations_last_message_at ON conversations (last_message_at);
+
+CREATE TABLE IF NOT EXISTS conversation_members (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    conversation_id text        NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    user_id         text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    joined_at       timestamptz NOT NULL,
+    last_read_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_members_conversation_user ON conversation_members (conversation_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_conversation_members_user_id ON conversation_members (user_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    conversation_id text          NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    sender_id       text          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content         varchar(1000) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages (conversation_id);
+
+CREATE TABLE IF NOT EXISTS message_deletions (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    message_id text NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_deletions_message_user ON message_deletions (message_id, user_id);
+
+CREATE TABLE IF NOT EXISTS lists (
+    id          text PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    owner_id    text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        varchar(64) NOT NULL,
+    description varchar(256),
+    private     boolean     NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS idx_lists_owner_id ON lists (owner_id);
+
+CREATE TABLE IF NOT EXISTS list_members (
+    id         text PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    list_id    text NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    user_id    text NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_list_members_list_user ON list_members (list_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_list_members_user_id ON list_members (user_id);
+
+-- Trigram indexes for user typeahead, covering both prefix matches and similarity ranking.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING GIN (lower(display_name) gin_trgm_ops);
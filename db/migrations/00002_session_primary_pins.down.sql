@@ -0,0 +1,3 @@
+-- Dropping the pins lets users who just wrote read from the replicas straight away.
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS primary_until;
@@ -0,0 +1,3 @@
+-- Sessions read from the primary database for a short window after their user wrote, so they see their own writes.
+
+ALTER TABLE sessions ADD COLUMN primary_until timestamptz;
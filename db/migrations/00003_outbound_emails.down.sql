@@ -0,0 +1,3 @@
+-- Dropping the outbox loses every email that has not been sent yet.
+
+DROP TABLE IF EXISTS outbound_emails;
@@ -0,0 +1,17 @@
+-- The outbox of emails waiting to be sent, written in the same transaction as the change that sends them.
+
+CREATE TABLE outbound_emails (
+    id              text PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    recipient       varchar(255) NOT NULL,
+    subject         varchar(512) NOT NULL,
+    template        varchar(64)  NOT NULL,
+    data            text         NOT NULL,
+    status          varchar(16)  NOT NULL DEFAULT 'pending',
+    attempts        bigint       NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz  NOT NULL,
+    last_error      text,
+    sent_at         timestamptz
+);
+CREATE INDEX idx_outbound_emails_status_next_attempt_at ON outbound_emails (status, next_attempt_at);
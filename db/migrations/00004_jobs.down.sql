@@ -0,0 +1,3 @@
+-- Dropping the job queue loses every job that has not run yet, and the dead jobs.
+
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,18 @@
+-- The background job queue, workers claim jobs with FOR UPDATE SKIP LOCKED so each job only runs on one instance.
+
+CREATE TABLE jobs (
+    id           text PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    kind         varchar(128) NOT NULL,
+    payload      text         NOT NULL,
+    unique_key   varchar(255),
+    status       varchar(16)  NOT NULL DEFAULT 'pending',
+    run_at       timestamptz  NOT NULL,
+    attempts     bigint       NOT NULL DEFAULT 0,
+    max_attempts bigint       NOT NULL DEFAULT 5,
+    locked_until timestamptz,
+    last_error   text
+);
+CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs (unique_key) WHERE status = 'pending';
+CREATE INDEX idx_jobs_status_run_at ON jobs (status, run_at);
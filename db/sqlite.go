@@ -0,0 +1,40 @@
+package db
+
+import (
+	"github.com/glebarez/sqlite"
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+)
+
+// OpenSQLite opens a SQLite database, such as file::memory:, and creates the schema from the models.
+// The migrations are written for Postgres, so SQLite is only meant for tests and tools that do not need Postgres,
+// and anything relying on Postgres itself, such as full-text search, is not available.
+func OpenSQLite(dsn string) (*gorm.DB, error) {
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		FullSaveAssociations: true,
+		TranslateError:       true, // translate driver errors such as unique violations into GORM errors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only allows a single writer, and every connection to an in-memory database would get its own copy,
+	// so a single connection is shared by everything.
+	sqlDB.SetMaxOpenConns(1)
+
+	// Foreign keys are off by default in SQLite, and the models rely on them to cascade deletes.
+	if err := conn.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		return nil, err
+	}
+
+	if err := conn.AutoMigrate(models.Models...); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -2,12 +2,10 @@ package mail
 
 import (
 	"bytes"
-	"crypto/tls"
 	"embed"
 	"encoding/json"
 	"github.com/twibber/core/cfg"
 	"log/slog"
-	"strconv"
 	"text/template"
 
 	"gopkg.in/gomail.v2"
@@ -21,85 +19,83 @@ var htmlTemplates embed.FS
 //go:embed templates/text/*
 var textTemplates embed.FS
 
-var (
-	mailer   *gomail.Dialer     // Mailer configuration for sending emails.
+// Mailer renders and sends emails.
+type Mailer struct {
+	config   *cfg.Configuration
 	textTmpl *template.Template // Compiled text templates for emails.
 	htmlTmpl *template.Template // Compiled HTML templates for emails.
-)
+}
+
+// New loads the email templates and configures the mailer.
+// In debug mode emails are only logged, so no mail server is needed.
+func New(config *cfg.Configuration) (*Mailer, error) {
+	m := &Mailer{config: config}
 
-func init() {
 	// Load and parse email templates.
-	loadTemplates()
+	if err := m.loadTemplates(); err != nil {
+		return nil, err
+	}
 
 	// If the application is in debug mode, do not initialize the mailer.
-	if cfg.Config.Debug {
+	if config.Debug {
 		slog.Debug("mailer initialized in debug mode")
-		return
-	}
-
-	// Initialize the mailer with configuration from the environment or config files.
-	port, err := strconv.Atoi(cfg.Config.MailPort)
-	if err != nil {
-		slog.With(
-			"error", err,
-			"port", cfg.Config.MailPort,
-		).Error("failed to parse mail port")
-		panic(err)
+		return m, nil
 	}
 
-	// Set up mailer with TLS configuration based on application security requirements.
-	mailer = gomail.NewDialer(cfg.Config.MailHost, port, cfg.Config.MailUsername, cfg.Config.MailPassword)
-	mailer.TLSConfig = &tls.Config{InsecureSkipVerify: !cfg.Config.MailSecure, ServerName: cfg.Config.MailHost}
-
-	slog.With("host", cfg.Config.MailHost,
-		"port", cfg.Config.MailPort,
-		"secure", cfg.Config.MailSecure,
-		"username", cfg.Config.MailUsername,
-		"sender", cfg.Config.MailSender,
-		"reply", cfg.Config.MailReply,
+	slog.With("host", config.MailHost,
+		"port", config.MailPort,
+		"secure", config.MailSecure,
+		"timeout", config.MailTimeout,
+		"username", config.MailUsername,
+		"sender", config.MailSender,
+		"reply", config.MailReply,
 	).Info("successfully configured")
+
+	return m, nil
 }
 
 // loadTemplates compiles the email templates from the embedded file system.
-func loadTemplates() {
+func (m *Mailer) loadTemplates() error {
 	var err error
-	htmlTmpl, err = template.ParseFS(htmlTemplates, "templates/html/*")
+	m.htmlTmpl, err = template.ParseFS(htmlTemplates, "templates/html/*")
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	textTmpl, err = template.ParseFS(textTemplates, "templates/text/*")
+	m.textTmpl, err = template.ParseFS(textTemplates, "templates/text/*")
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	slog.With("html", "templates/html/*",
 		"text", "templates/text/*",
 	).Info("successfully loaded templates")
+
+	return nil
 }
 
 // Send constructs and sends an email using the specified subject, template, and data.
-func Send(subject, templateName string, data interface{}) error {
+func (m *Mailer) Send(subject, templateName string, data interface{}) error {
 	var htmlEmail, textEmail bytes.Buffer
 
 	// Execute HTML template.
-	if err := htmlTmpl.ExecuteTemplate(&htmlEmail, templateName+".html", data); err != nil {
+	if err := m.htmlTmpl.ExecuteTemplate(&htmlEmail, templateName+".html", data); err != nil {
 		slog.With("template", templateName+".html").Error("failed to execute HTML template")
 		return err
 	}
 
 	// Execute text template.
-	if err := textTmpl.ExecuteTemplate(&textEmail, templateName+".txt", data); err != nil {
+	if err := m.textTmpl.ExecuteTemplate(&textEmail, templateName+".txt", data); err != nil {
 		slog.With("template", templateName+".txt").Error("failed to execute text template")
 		return err
 	}
 
 	// Send the email.
-	return sendEmail(subject, data, textEmail.String(), htmlEmail.String())
+	return m.sendEmail(subject, data, textEmail.String(), htmlEmail.String())
 }
 
 // sendEmail configures the email message and sends it.
-func sendEmail(subject string, data interface{}, textContent, htmlContent string) error {
+func (m *Mailer) sendEmail(subject string, data interface{}, textContent, htmlContent string) error {
 	var defaultData Defaults
 
 	// Marshal and unmarshal the data to ensure it is in the correct format.
@@ -123,16 +119,16 @@ func sendEmail(subject string, data interface{}, textContent, htmlContent string
 
 	// Create the email message.
 	msg := gomail.NewMessage()
-	msg.SetHeader("From", cfg.Config.MailSender)
+	msg.SetHeader("From", m.config.MailSender)
 	msg.SetAddressHeader("To", defaultData.Email, defaultData.Name)
 	msg.SetHeader("Subject", subject)
 	msg.SetBody("text/plain", textContent)
 	msg.AddAlternative("text/html", htmlContent)
 
 	// In debug mode, log the email details instead of sending.
-	if cfg.Config.Debug {
+	if m.config.Debug {
 		slog.With(
-			"from", cfg.Config.MailSender,
+			"from", m.config.MailSender,
 			"to", defaultData.Email,
 			"subject", subject,
 			"data", data,
@@ -141,5 +137,5 @@ func sendEmail(subject string, data interface{}, textContent, htmlContent string
 	}
 
 	// Send the email.
-	return mailer.DialAndSend(msg)
+	return m.deliver(msg, m.config.MailSender, defaultData.Email)
 }
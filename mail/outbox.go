@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"encoding/json"
+	"github.com/twibber/core/app/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Queue adds an email to the outbox, to be sent by a background job once the transaction commits.
+// The template data is stored as JSON, so it must only be made of fields that survive being encoded,
+// and must not hold secrets, anything that expires is generated as the email is sent, see Preparer.
+func (m *Mailer) Queue(tx *gorm.DB, subject, templateName string, data Data) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboundEmail{
+		Recipient:     data.Recipient().Email,
+		Subject:       subject,
+		Template:      templateName,
+		Data:          string(encoded),
+		Status:        models.OutboundEmailPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// preparedData creates the template data of the emails whose data is prepared as they are sent, by their template.
+var preparedData = map[string]func() Data{
+	verifyTemplate: func() Data { return &VerifyDTO{} },
+}
+
+// SendOutbound renders and sends an email from the outbox, preparing its data first if its template needs it.
+func (m *Mailer) SendOutbound(tx *gorm.DB, email models.OutboundEmail) error {
+	newData, ok := preparedData[email.Template]
+	if !ok {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(email.Data), &data); err != nil {
+			return err
+		}
+
+		return m.Send(email.Subject, email.Template, data)
+	}
+
+	data := newData()
+	if err := json.Unmarshal([]byte(email.Data), data); err != nil {
+		return err
+	}
+	if preparer, ok := data.(Preparer); ok {
+		if err := preparer.Prepare(tx, m.config); err != nil {
+			return err
+		}
+	}
+
+	return m.Send(email.Subject, email.Template, data)
+}
+
+// RetryDelay is how long to wait before the next attempt at sending an email that has failed attempts times.
+// The delay doubles after each attempt, up to MAIL_RETRY_MAX_DELAY.
+func (m *Mailer) RetryDelay(attempts int) time.Duration {
+	delay := m.config.MailRetryDelay
+	for i := 1; i < attempts && delay < m.config.MailRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, m.config.MailRetryMaxDelay)
+}
+
+// ListOutbound lists the emails in the outbox with the status, or every email when the status is empty, newest first.
+func ListOutbound(db *gorm.DB, status models.OutboundEmailStatus, limit int) ([]models.OutboundEmail, error) {
+	query := db.Order("created_at desc").Limit(limit)
+	if status != "" {
+		query = query.Where(models.OutboundEmail{Status: status})
+	}
+
+	var emails []models.OutboundEmail
+	err := query.Find(&emails).Error
+	return emails, err
+}
+
+// RetryOutbound sends emails that have not been sent again straight away, with their attempts reset,
+// returning how many were retried. Sent emails are left alone so they are never sent twice.
+func RetryOutbound(db *gorm.DB, ids ...string) (int64, error) {
+	return retryOutbound(db.Where("id IN ?", ids))
+}
+
+// RetryDeadOutbound sends every dead email again straight away, with their attempts reset, returning how many were retried.
+func RetryDeadOutbound(db *gorm.DB) (int64, error) {
+	return retryOutbound(db.Where(models.OutboundEmail{Status: models.OutboundEmailDead}))
+}
+
+func retryOutbound(query *gorm.DB) (int64, error) {
+	result := query.
+		Model(&models.OutboundEmail{}).
+		Where("status <> ?", models.OutboundEmailSent).
+		Updates(map[string]interface{}{
+			"status":          models.OutboundEmailPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
@@ -1,6 +1,22 @@
 package mail
 
-import "github.com/twibber/core/cfg"
+import (
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/utils"
+	"gorm.io/gorm"
+)
+
+// Data is the template data of an email, which includes who the email is for.
+type Data interface {
+	Recipient() Defaults
+}
+
+// Preparer is template data that is completed as the email is sent rather than when it is queued,
+// such as codes that would expire while the email waits in the outbox.
+type Preparer interface {
+	Prepare(tx *gorm.DB, config *cfg.Configuration) error
+}
 
 // Defaults struct holds common fields for all email types.
 type Defaults struct {
@@ -8,14 +24,50 @@ type Defaults struct {
 	Name  string // Recipient's name
 }
 
+// Recipient returns who the email is for.
+func (d Defaults) Recipient() Defaults {
+	return d
+}
+
+// verifyTemplate is the template of verification emails.
+const verifyTemplate = "user_verify"
+
 // VerifyDTO is a data structure for verification emails.
+// Only the connection is kept in the outbox, the code is generated by Prepare as the email is sent,
+// so it is still valid however long delivery took and is never stored.
 type VerifyDTO struct {
 	Defaults
-	Code string
+	ConnectionID string
+	Code         string `json:",omitempty"`
 }
 
-// Send dispatches a verification email using predefined template and subject.
-func (data VerifyDTO) Send() error {
+// Queue adds a verification email to the outbox using predefined template and subject, as part of the transaction.
+func (data VerifyDTO) Queue(tx *gorm.DB, m *Mailer) error {
+	data.Code = ""
+
 	// The template name "user_verify" should match a template file name (without extension)
-	return Send("Verify your "+cfg.Config.Name+" Account", "user_verify", data)
+	return m.Queue(tx, "Verify your "+m.config.Name+" Account", verifyTemplate, data)
+}
+
+// Prepare generates the current verification code of the connection.
+func (data *VerifyDTO) Prepare(tx *gorm.DB, config *cfg.Configuration) error {
+	// Emails queued before the code was generated on sending already hold their code.
+	if data.ConnectionID == "" {
+		return nil
+	}
+
+	var connection models.Connection
+	if err := tx.Where(models.Connection{
+		BaseModel: models.BaseModel{ID: data.ConnectionID},
+	}).First(&connection).Error; err != nil {
+		return err
+	}
+
+	code, err := utils.GenerateTOTP(connection.TOTPVerify, config.EmailCodeStep)
+	if err != nil {
+		return err
+	}
+
+	data.Code = code
+	return nil
 }
@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// deliver sends the message to the mail server, giving up once MAIL_TIMEOUT has passed.
+// gomail only limits how long connecting takes, so a server that stops responding part way through would otherwise
+// hold up the rest of the outbox indefinitely. The whole conversation shares a single deadline on the connection.
+func (m *Mailer) deliver(msg *gomail.Message, from, to string) error {
+	timeout := m.config.MailTimeout
+	host := m.config.MailHost
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", host, m.config.MailPort), timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	// Port 465 expects TLS straight away, every other port is upgraded with STARTTLS when the server supports it.
+	tlsConfig := &tls.Config{InsecureSkipVerify: !m.config.MailSecure, ServerName: host}
+	if m.config.MailPort == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.config.MailPort != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.config.MailUsername != "" {
+		if ok, mechanisms := c.Extension("AUTH"); ok {
+			if err := c.Auth(m.auth(mechanisms)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// auth picks the authentication mechanism the same way gomail does, preferring CRAM-MD5,
+// then PLAIN, and falling back to LOGIN for servers that only support it.
+func (m *Mailer) auth(mechanisms string) smtp.Auth {
+	switch {
+	case strings.Contains(mechanisms, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(m.config.MailUsername, m.config.MailPassword)
+	case strings.Contains(mechanisms, "LOGIN") && !strings.Contains(mechanisms, "PLAIN"):
+		return &loginAuth{username: m.config.MailUsername, password: m.config.MailPassword}
+	default:
+		return smtp.PlainAuth("", m.config.MailUsername, m.config.MailPassword, m.config.MailHost)
+	}
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp does not provide.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
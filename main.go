@@ -1,20 +1,85 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/twibber/core/app"
+	"github.com/twibber/core/app/jobs"
 	"github.com/twibber/core/app/routes"
 	"github.com/twibber/core/cfg"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 // main is the entry point for the application
 func main() {
+	// Load the configuration, refusing to start with every misconfigured key listed at once.
+	config, err := cfg.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Set log/slog to use the debug setting
+	if config.Debug {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	} else {
+		slog.SetLogLoggerLevel(slog.LevelInfo)
+	}
+
+	// Run the migrate or outbox subcommand instead of the server when one is given
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(migrate(config, os.Args[2:]))
+		case "outbox":
+			os.Exit(outbox(config, os.Args[2:]))
+		}
+	}
+
+	// Connect to the database and set up the rest of the application's dependencies
+	a, err := app.New(config)
+	if err != nil {
+		slog.With("error", err).Error("failed to initialise the application")
+		os.Exit(1)
+	}
+
+	// Listen for the process being told to stop, so it can shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
 	// Log the server start
-	slog.With("port", cfg.Config.Port, "debug", cfg.Config.Debug).Info("starting server")
+	slog.With("port", config.Port, "debug", config.Debug).Info("starting server")
+
+	// Start running the background jobs, such as publishing scheduled posts and sending emails
+	queue := jobs.Configure(a)
+	queue.Start()
 
 	// Configure the routes and start the server
-	if err := routes.Configure().Listen(fmt.Sprintf("%s:%s", "0.0.0.0", cfg.Config.Port)); err != nil {
-		// if the server fails to start, panic with the error
-		panic(err)
+	server := routes.Configure(a)
+	go func() {
+		if err := server.Listen(fmt.Sprintf("%s:%d", "0.0.0.0", config.Port)); err != nil {
+			// if the server fails to start, panic with the error
+			panic(err)
+		}
+	}()
+
+	// Wait until the process is told to stop
+	<-ctx.Done()
+	stop()
+
+	// Give the open requests and the running jobs a chance to finish, jobs that do not are released to run again
+	slog.With("timeout", config.ShutdownTimeout.String()).Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	// End the open event streams first, they would otherwise hold the server open until the timeout
+	a.Broker.Close()
+	if err := server.ShutdownWithContext(ctx); err != nil {
+		slog.With("error", err).Error("failed to shut down the server")
+	}
+	if err := queue.Stop(ctx); err != nil {
+		slog.With("error", err).Warn("stopped before every running job finished")
 	}
 }
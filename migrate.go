@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// migrateUsage describes the migrate subcommand.
+const migrateUsage = `usage: core migrate <command>
+
+commands:
+  up        apply every pending migration
+  down [n]  roll back the latest n migrations, 1 by default
+  status    list the migrations and when they were applied
+  check     make sure the models agree with the migrated schema`
+
+// migrate runs the migrate subcommand with its arguments, returning the exit code.
+func migrate(config *cfg.Configuration, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	conn, err := db.Open(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("applied %d migrations\n", len(done))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "down: the number of migrations must be a positive integer")
+				return 2
+			}
+		}
+
+		done, err := migrator.Down(steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, migration := range done {
+			fmt.Printf("rolled back %05d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%05d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		_ = w.Flush()
+	case "check":
+		problems, err := migrator.Check()
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		if err != nil {
+			if !errors.Is(err, db.ErrSchemaMismatch) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return 1
+		}
+		fmt.Println("the models agree with the migrated schema")
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/twibber/core/app/models"
+	"github.com/twibber/core/cfg"
+	"github.com/twibber/core/db"
+	"github.com/twibber/core/mail"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// outboxUsage describes the outbox subcommand.
+const outboxUsage = `usage: core outbox <command>
+
+commands:
+  list [status]    list the latest 100 emails, optionally only those that are pending, sent or dead
+  show <id>        show an email along with its template data
+  retry <id>...    send emails that have not been sent again straight away, with their attempts reset
+  retry --dead     send every dead email again straight away, with their attempts reset`
+
+// outboxListLimit is the most emails listed by the list command.
+const outboxListLimit = 100
+
+// outbox runs the outbox subcommand with its arguments, returning the exit code.
+func outbox(config *cfg.Configuration, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, outboxUsage)
+		return 2
+	}
+
+	conn, err := db.Open(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		var status models.OutboundEmailStatus
+		if len(args) > 1 {
+			status = models.OutboundEmailStatus(args[1])
+			if !slices.Contains([]models.OutboundEmailStatus{models.OutboundEmailPending, models.OutboundEmailSent, models.OutboundEmailDead}, status) {
+				fmt.Fprintln(os.Stderr, "list: the status must be pending, sent or dead")
+				return 2
+			}
+		}
+
+		emails, err := mail.ListOutbound(conn, status, outboxListLimit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tATTEMPTS\tRECIPIENT\tTEMPLATE\tCREATED\tNEXT ATTEMPT\tLAST ERROR")
+		for _, email := range emails {
+			nextAttempt := "-"
+			if email.Status == models.OutboundEmailPending {
+				nextAttempt = email.NextAttemptAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", email.ID, email.Status, email.Attempts, email.Recipient,
+				email.Template, email.CreatedAt.Format(time.RFC3339), nextAttempt, email.LastError)
+		}
+		_ = w.Flush()
+	case "show":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "show: the id of a single email is required")
+			return 2
+		}
+
+		var email models.OutboundEmail
+		if err := conn.Where(models.OutboundEmail{BaseModel: models.BaseModel{ID: args[1]}}).First(&email).Error; err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "id\t%s\n", email.ID)
+		fmt.Fprintf(w, "status\t%s\n", email.Status)
+		fmt.Fprintf(w, "recipient\t%s\n", email.Recipient)
+		fmt.Fprintf(w, "subject\t%s\n", email.Subject)
+		fmt.Fprintf(w, "template\t%s\n", email.Template)
+		fmt.Fprintf(w, "data\t%s\n", email.Data)
+		fmt.Fprintf(w, "attempts\t%d\n", email.Attempts)
+		fmt.Fprintf(w, "created\t%s\n", email.CreatedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "next attempt\t%s\n", email.NextAttemptAt.Format(time.RFC3339))
+		if email.SentAt != nil {
+			fmt.Fprintf(w, "sent\t%s\n", email.SentAt.Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "last error\t%s\n", email.LastError)
+		_ = w.Flush()
+	case "retry":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "retry: the ids of the emails, or --dead, are required")
+			return 2
+		}
+
+		var retried int64
+		if args[1] == "--dead" {
+			retried, err = mail.RetryDeadOutbound(conn)
+		} else {
+			retried, err = mail.RetryOutbound(conn, args[1:]...)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("retrying %d emails\n", retried)
+	default:
+		fmt.Fprintln(os.Stderr, outboxUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -3,25 +3,20 @@ package utils
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/twibber/core/app/models"
-	"github.com/twibber/core/cfg"
-	"github.com/twibber/core/db"
+	"github.com/twibber/core/app/repository"
 	"time"
 )
 
 // AuthCookieName is the name of the cookie used to store the session token.
 const AuthCookieName = "Authorization"
 
-// AuthDuration is the duration of the Authorization cookie it's also used for the token expiration.
-const AuthDuration = time.Hour * 24 * 7 // 1 week
-
 // SetAuthCookie sets the Authorization cookie with the token and the duration.
-func SetAuthCookie(c *fiber.Ctx, token string, expiration time.Time) {
+func SetAuthCookie(c *fiber.Ctx, domain, token string, expiration time.Time) {
 	c.Cookie(&fiber.Cookie{
-		Name:   AuthCookieName,
-		Value:  token,
-		Path:   "/",
-		Domain: cfg.Config.Domain,
-		// MaxAge:   AuthDuration,
+		Name:     AuthCookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   domain,
 		Expires:  expiration,
 		HTTPOnly: true,
 		SameSite: "lax",
@@ -29,48 +24,68 @@ func SetAuthCookie(c *fiber.Ctx, token string, expiration time.Time) {
 }
 
 // ClearAuth clears the Authorization cookie by setting the MaxAge to 0 and replacing the value with an empty string.
-func ClearAuth(c *fiber.Ctx) {
+func ClearAuth(c *fiber.Ctx, domain string) {
 	c.Cookie(&fiber.Cookie{
 		Name:     AuthCookieName,
 		Value:    "",
 		Path:     "/",
-		Domain:   cfg.Config.Domain,
+		Domain:   domain,
 		MaxAge:   0,
 		HTTPOnly: true,
 		SameSite: "lax",
 	})
 }
 
-// GetUserID returns the session from the Authorization cookie.
-func GetUserID(c *fiber.Ctx) string {
+// GetSession returns the session from the Authorization cookie, if it is valid.
+// The session is kept on the request, so it is only looked up once however often it is needed.
+func GetSession(c *fiber.Ctx, sessions repository.SessionRepository) (models.Session, bool) {
 	// Check if session is already attached to the context
-	session, ok := c.Locals("session").(models.Session)
+	if session, ok := c.Locals("session").(models.Session); ok {
+		return session, true
+	}
 
-	// If session is already attached to the context, return the user id
-	if ok {
-		return session.Connection.UserID
+	// Check if the session was already looked up from the cookie, it is nil when there was no valid session.
+	if session, ok := c.Locals("cookie_session").(*models.Session); ok {
+		if session == nil {
+			return models.Session{}, false
+		}
+		return *session, true
 	}
 
-	// If session is not attached to the context, get it from the cookie
+	session := lookupSession(c, sessions)
+	c.Locals("cookie_session", session)
+	if session == nil {
+		return models.Session{}, false
+	}
+
+	return *session, true
+}
+
+// lookupSession gets the session from the database using the Authorization cookie, it returns nil if there is no valid session.
+func lookupSession(c *fiber.Ctx, sessions repository.SessionRepository) *models.Session {
 	authCookie := c.Cookies(AuthCookieName)
 	if authCookie == "" {
-		return ""
+		return nil
 	}
 
 	// Get the session from the database using the cookie with the connection preloaded to get the user id from
-	if err := db.DB.Where(models.Session{
-		BaseModel: models.BaseModel{
-			ID: authCookie,
-		},
-	}).
-		Preload("Connection").
-		First(&session).
-		Error; err != nil {
-		return ""
+	session, err := sessions.Find(authCookie)
+	if err != nil {
+		return nil
 	}
 
 	// Check if token is expired
 	if time.Now().After(session.ExpiresAt) {
+		return nil
+	}
+
+	return &session
+}
+
+// GetUserID returns the id of the user signed in with the Authorization cookie, or an empty string when signed out.
+func GetUserID(c *fiber.Ctx, sessions repository.SessionRepository) string {
+	session, ok := GetSession(c, sessions)
+	if !ok {
 		return ""
 	}
 
@@ -102,6 +102,11 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 		}
 	}
 
+	// Handle unique constraint violations, such as two concurrent requests creating the same record.
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		e = NewError(fiber.StatusConflict, "The resource already exists.", nil)
+	}
+
 	// Handle all fiber errors
 	var fiberErr *fiber.Error
 	if errors.As(err, &fiberErr) {
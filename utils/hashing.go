@@ -5,6 +5,7 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"github.com/twibber/core/cfg"
 	"golang.org/x/crypto/argon2"
 	"strings"
 )
@@ -18,7 +19,7 @@ type ArgonParams struct {
 	keyLength   uint32 // Length of the generated key
 }
 
-// DefaultArgonParams provides default settings for Argon2 parameters.
+// DefaultArgonParams provides default settings for Argon2 parameters, they match the defaults of the ARGON_* configuration.
 var DefaultArgonParams = ArgonParams{
 	memory:      64 * 1024, // 64 MB
 	iterations:  3,
@@ -27,6 +28,17 @@ var DefaultArgonParams = ArgonParams{
 	keyLength:   32, // 32 bytes
 }
 
+// ArgonParamsFromConfig provides the configured settings for Argon2 parameters used for new hashes.
+func ArgonParamsFromConfig(config *cfg.Configuration) ArgonParams {
+	return ArgonParams{
+		memory:      uint32(config.ArgonMemory),
+		iterations:  uint32(config.ArgonIterations),
+		parallelism: uint8(config.ArgonParallelism),
+		saltLength:  uint32(config.ArgonSaltLength),
+		keyLength:   uint32(config.ArgonKeyLength),
+	}
+}
+
 // Predefined errors for hash validation and processing.
 var (
 	ErrInvalidHash         = fmt.Errorf("the encoded hash is not in the correct format")
@@ -34,22 +46,22 @@ var (
 )
 
 // CreateHash generates a hash for a given password using Argon2.
-func CreateHash(password string) (encodedHash string, err error) {
+func CreateHash(password string, params ArgonParams) (encodedHash string, err error) {
 	// Generate a cryptographically secure random salt.
-	salt, err := GenerateRandomBytes(DefaultArgonParams.saltLength)
+	salt, err := GenerateRandomBytes(params.saltLength)
 	if err != nil {
 		return "", err
 	}
 
 	// Generate the hash using Argon2id with the provided password and salt.
-	hash := argon2.IDKey([]byte(password), salt, DefaultArgonParams.iterations, DefaultArgonParams.memory, DefaultArgonParams.parallelism, DefaultArgonParams.keyLength)
+	hash := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
 
 	// Encode the salt and hash in base64 for storage.
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
 	// Format the final encoded hash string with all parameters for verification later.
-	encodedHash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, DefaultArgonParams.memory, DefaultArgonParams.iterations, DefaultArgonParams.parallelism, b64Salt, b64Hash)
+	encodedHash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.memory, params.iterations, params.parallelism, b64Salt, b64Hash)
 
 	return encodedHash, nil
 }
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// DefaultPageLimit is the number of records per page used when the request does not provide a limit.
+const DefaultPageLimit = 20
+
+// Pagination holds the page and limit requested through the query string.
+type Pagination struct {
+	Page  int `query:"page" validate:"omitempty,min=1"`
+	Limit int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// ParsePagination parses and validates the pagination query parameters, filling in the defaults.
+func ParsePagination(c *fiber.Ctx) (Pagination, error) {
+	var p Pagination
+	if err := ParseQueryAndValidate(c, &p); err != nil {
+		return p, err
+	}
+
+	if p.Page == 0 {
+		p.Page = 1
+	}
+
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+
+	return p, nil
+}
+
+// Paginate is a GORM scope that applies the offset and limit of the page.
+func (p Pagination) Paginate(db *gorm.DB) *gorm.DB {
+	return db.Offset((p.Page - 1) * p.Limit).Limit(p.Limit)
+}
@@ -17,21 +17,6 @@ const (
 	codeLen = 6
 )
 
-// StepDurationType defines the type of TOTP duration.
-type StepDurationType int
-
-// These constants represent the two types of durations.
-const (
-	MFACode           StepDurationType = iota // For Multi-Factor Authentication.
-	EmailVerification                         // For email code verification.
-)
-
-// stepDurations map duration types to their respective durations.
-var stepDurations = [...]int64{
-	30,  // MFA code lasts 30 seconds.
-	600, // Email verification code lasts 10 minutes.
-}
-
 var chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // GenerateString produces a random string of the specified length.
@@ -80,7 +65,7 @@ func GenerateSecureRandomBase32(n int) (string, error) {
 func ComputeTOTP(secret string, timestamp int64) (string, error) {
 	key, err := base32.StdEncoding.DecodeString(strings.ToUpper(secret))
 	if err != nil {
-		slog.Error("Error decoding secret: ", err)
+		slog.With("error", err).Error("error decoding secret")
 		return "", err
 	}
 
@@ -100,19 +85,21 @@ func ComputeTOTP(secret string, timestamp int64) (string, error) {
 	return strCode, nil
 }
 
-// GenerateTOTP provides a TOTP code for the current time and desired type (MFA or Email Verification).
-func GenerateTOTP(secret string, stepType StepDurationType) (string, error) {
-	if stepType < 0 || int(stepType) >= len(stepDurations) {
-		return "", errors.New("invalid stepType provided")
+// GenerateTOTP provides a TOTP code for the current time and the step, such as the configured MFA or email verification step.
+// The step must be a whole number of seconds.
+func GenerateTOTP(secret string, step time.Duration) (string, error) {
+	seconds := int64(step / time.Second)
+	if seconds <= 0 {
+		return "", errors.New("invalid step provided")
 	}
-	return ComputeTOTP(secret, time.Now().Unix()/stepDurations[stepType])
+	return ComputeTOTP(secret, time.Now().Unix()/seconds)
 }
 
-// ValidateTOTP verifies if the provided code matches the expected TOTP value for the given secret and duration type.
-func ValidateTOTP(secret, code string, stepType StepDurationType) bool {
-	expectedCode, err := GenerateTOTP(secret, stepType)
+// ValidateTOTP verifies if the provided code matches the expected TOTP value for the given secret and step.
+func ValidateTOTP(secret, code string, step time.Duration) bool {
+	expectedCode, err := GenerateTOTP(secret, step)
 	if err != nil {
-		slog.Error("Error generating TOTP: ", err)
+		slog.With("error", err).Error("error generating TOTP")
 		return false
 	}
 	return subtleCompare(code, expectedCode)
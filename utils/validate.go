@@ -17,6 +17,22 @@ func ParseAndValidate(c *fiber.Ctx, body any) error {
 		return err
 	}
 
+	return validate(body)
+}
+
+// ParseQueryAndValidate parses the query string into the provided struct and validates it.
+// Returns a detailed error if validation fails.
+func ParseQueryAndValidate(c *fiber.Ctx, query any) error {
+	// Parse the query string into the provided struct.
+	if err := c.QueryParser(query); err != nil {
+		return err
+	}
+
+	return validate(query)
+}
+
+// validate validates the provided struct and converts validation errors into a detailed error response.
+func validate(body any) error {
 	// Start a new validator instance.
 	v := validator.New()
 